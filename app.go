@@ -2,38 +2,73 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const (
+	defaultHistoryPageSize = 50
+	maxHistoryPageSize     = 200
+)
+
 type App struct {
-	ctx context.Context
-	hub *Hub
+	ctx    context.Context
+	hub    *Hub
+	store  Store
+	quit   chan struct{} // закрывается при завершении, останавливает фоновые задачи
+	server *http.Server  // отдаёт /ws, /health и вложения, см. server.go
+	blobs  *BlobStore    // файлы вложений, см. blobs.go
+
+	markersMu     sync.Mutex         // сериализует сдвиг отметок прочтения
+	prefsMu       sync.Mutex         // сериализует изменение настроек пользователей
+	pinsMu        sync.Mutex         // сериализует изменение закреплённых сообщений
+	notifications notificationCenter // уведомления рабочего стола, см. notifications.go
+	search        searchIndex        // поиск по сообщениям, см. search.go
 }
 
-func NewApp() *App {
-	initRedis()
-	hub := NewHub()
+func NewApp(store Store) *App {
+	userManager = NewUserManager(store)
+	hub := NewHub(store)
 	go hub.Run()
-	userManager.LoadUsersFromRedis()
+	userManager.LoadUsersFromStore()
 
 	// ✅ ДОБАВЛЕНО - сбрасываем все статусы в offline при старте
 	userManager.ResetAllStatusesToOffline()
 
-	return &App{hub: hub}
+	blobs, err := NewBlobStoreFromEnv()
+	if err != nil {
+		log.Printf("❌ Вложения недоступны: %v", err)
+	}
+
+	app := &App{hub: hub, store: store, blobs: blobs, quit: make(chan struct{})}
+	hub.app = app
+	return app
 }
 
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
 	a.initDefaultChannels()
+	go a.runCompaction(compactionInterval())
+	go a.rebuildSearchIndex()
+	a.startServer()
 	log.Println("✓ GoThermo запущен")
 }
 
+func (a *App) shutdown(ctx context.Context) {
+	close(a.quit)
+	a.stopServer()
+	log.Println("✓ GoThermo остановлен")
+}
+
 func (a *App) initDefaultChannels() {
-	channels, err := GetAllChannels()
+	channels, err := a.store.GetAllChannels()
 	if err != nil {
 		log.Printf("Ошибка получения каналов: %v", err)
 		return
@@ -48,7 +83,7 @@ func (a *App) initDefaultChannels() {
 		{ID: uuid.New().String(), Name: "dev-team", Description: "Development team", Members: []string{}, CreatedBy: "system", CreatedAt: time.Now(), IsPrivate: false},
 	}
 	for _, channel := range defaultChannels {
-		if err := SaveChannel(channel); err != nil {
+		if err := a.store.SaveChannel(channel); err != nil {
 			log.Printf("Ошибка создания канала %s: %v", channel.Name, err)
 		} else {
 			log.Printf("✓ Канал создан: #%s", channel.Name)
@@ -57,33 +92,34 @@ func (a *App) initDefaultChannels() {
 }
 
 func (a *App) SendMessage(user, text, channel string) (string, error) {
-	if text == "" {
-		return "", fmt.Errorf("сообщение не может быть пустым")
-	}
-	msg := Message{ID: uuid.New().String(), User: user, Text: text, Channel: channel, Timestamp: time.Now(), Reactions: make(map[string][]string), IsPost: false}
-	if err := SaveMessage(msg); err != nil {
-		return "", fmt.Errorf("не удалось сохранить сообщение: %v", err)
-	}
-	a.hub.BroadcastToChannel(channel, msg)
-	log.Printf("📨 %s -> #%s: %s", user, channel, truncate(text, 50))
-	return msg.ID, nil
+	return a.SendMessageWithAttachments(user, text, channel, nil)
 }
 
 func (a *App) SendPost(user, text, channel string) (string, error) {
 	if text == "" {
 		return "", fmt.Errorf("пост не может быть пустым")
 	}
+	if err := a.checkChannelAccess(channel, user); err != nil {
+		return "", err
+	}
 	msg := Message{ID: uuid.New().String(), User: user, Text: text, Channel: channel, Timestamp: time.Now(), Reactions: make(map[string][]string), IsPost: true}
-	if err := SaveMessage(msg); err != nil {
+	a.resolveMentions(&msg)
+	if err := a.store.SaveMessage(msg); err != nil {
 		return "", fmt.Errorf("не удалось сохранить пост: %v", err)
 	}
+	a.search.add(msg)
 	a.hub.BroadcastToChannel(channel, msg)
+	a.notifyMentions(msg)
+	a.notifyDesktop(msg)
 	log.Printf("📌 %s создал пост в #%s: %s", user, channel, truncate(text, 50))
 	return msg.ID, nil
 }
 
-func (a *App) GetMessages(channel string) ([]Message, error) {
-	messages, err := GetMessages(channel, 100)
+func (a *App) GetMessages(channel, username string) ([]Message, error) {
+	if err := a.checkChannelAccess(channel, username); err != nil {
+		return []Message{}, err
+	}
+	messages, err := a.store.GetMessages(channel, 100)
 	if err != nil {
 		log.Printf("Ошибка получения сообщений из #%s: %v", channel, err)
 		return []Message{}, nil
@@ -91,76 +127,222 @@ func (a *App) GetMessages(channel string) ([]Message, error) {
 	return messages, nil
 }
 
+// GetMessageHistory возвращает страницу истории канала для бесконечной прокрутки.
+// before/after - ID сообщения или время в RFC3339, передаётся не больше одного
+// из них. Без курсора возвращаются последние сообщения.
+func (a *App) GetMessageHistory(channel, username, before, after string, limit int) (MessagePage, error) {
+	if err := a.checkChannelAccess(channel, username); err != nil {
+		return MessagePage{}, err
+	}
+	if before != "" && after != "" {
+		return MessagePage{}, fmt.Errorf("укажите только один курсор: before или after")
+	}
+	if limit <= 0 {
+		limit = defaultHistoryPageSize
+	}
+	if limit > maxHistoryPageSize {
+		limit = maxHistoryPageSize
+	}
+
+	query := HistoryQuery{Before: before, After: after, Limit: int64(limit)}
+	page, err := a.store.GetMessageHistory(channel, query)
+	if err == ErrNotFound {
+		return MessagePage{}, fmt.Errorf("сообщение-курсор не найдено")
+	}
+	if err != nil {
+		log.Printf("Ошибка получения истории #%s: %v", channel, err)
+		return MessagePage{}, fmt.Errorf("не удалось получить историю: %v", err)
+	}
+	return page, nil
+}
+
 func (a *App) AddReaction(messageID, emoji, username, channel string) error {
-	messages, err := GetMessages(channel, 1000)
+	if err := a.checkChannelAccess(channel, username); err != nil {
+		return err
+	}
+	reactions, err := a.store.ToggleReaction(channel, messageID, emoji, username)
+	if err == ErrNotFound {
+		return fmt.Errorf("сообщение не найдено")
+	}
 	if err != nil {
-		return fmt.Errorf("не удалось получить сообщения: %v", err)
+		return fmt.Errorf("не удалось обновить реакцию: %v", err)
+	}
+	a.hub.BroadcastReactionUpdate(ReactionUpdate{
+		Channel:   channel,
+		MessageID: messageID,
+		Emoji:     emoji,
+		Username:  username,
+		Added:     contains(reactions[emoji], username),
+		Reactions: reactions,
+	})
+	return nil
+}
+
+// errMessageUnchanged прерывает ModifyMessage, когда правка не меняет
+// текст: сообщение не перезаписывается и правка не рассылается
+var errMessageUnchanged = errors.New("текст сообщения не изменился")
+
+// EditMessage меняет текст сообщения. Редактировать может только автор,
+// прежний текст сохраняется в истории правок.
+func (a *App) EditMessage(messageID, channel, user, newText string) error {
+	if newText == "" {
+		return fmt.Errorf("сообщение не может быть пустым")
 	}
-	var foundMsg *Message
-	for _, msg := range messages {
-		if msg.ID == messageID {
-			foundMsg = &msg
-			break
+	msg, err := a.store.ModifyMessage(channel, messageID, func(msg *Message) error {
+		if msg.User != user {
+			return fmt.Errorf("редактировать сообщение может только автор")
+		}
+		if msg.Text == newText {
+			return errMessageUnchanged
+		}
+		writtenAt := msg.Timestamp
+		if msg.EditedAt != nil {
+			writtenAt = *msg.EditedAt
 		}
+		now := time.Now()
+		msg.Revisions = append(msg.Revisions, MessageRevision{Text: msg.Text, Timestamp: writtenAt})
+		msg.Text = newText
+		msg.EditedAt = &now
+		return nil
+	})
+	if err == errMessageUnchanged {
+		return nil
 	}
-	if foundMsg == nil {
+	if err == ErrNotFound {
 		return fmt.Errorf("сообщение не найдено")
 	}
-	if foundMsg.Reactions == nil {
-		foundMsg.Reactions = make(map[string][]string)
+	if err != nil {
+		return err
 	}
-	users := foundMsg.Reactions[emoji]
-	newUsers := []string{}
-	alreadyReacted := false
-	for _, u := range users {
-		if u == username {
-			alreadyReacted = true
-			continue
+	a.search.add(*msg)
+	a.hub.BroadcastMessageEdited(channel, *msg)
+	log.Printf("✏️ %s отредактировал сообщение в #%s: %s", user, channel, truncate(newText, 50))
+	return nil
+}
+
+// DeleteMessage удаляет сообщение. Удалить может автор или создатель канала.
+// Вместе с корнем треда удаляются его ответы и подписки.
+func (a *App) DeleteMessage(messageID, channel, username string) error {
+	msg, err := a.store.GetMessage(channel, messageID)
+	if err == ErrNotFound {
+		return fmt.Errorf("сообщение не найдено")
+	}
+	if err != nil {
+		return fmt.Errorf("не удалось получить сообщение: %v", err)
+	}
+	if msg.User != username {
+		ch, err := a.store.GetChannel(rootChannel(channel))
+		if err != nil || ch.CreatedBy != username {
+			return fmt.Errorf("удалить сообщение может только автор или создатель канала")
 		}
-		newUsers = append(newUsers, u)
 	}
-	if !alreadyReacted {
-		newUsers = append(newUsers, username)
+	if err := a.store.DeleteMessage(channel, messageID); err != nil && err != ErrNotFound {
+		return fmt.Errorf("не удалось удалить сообщение: %v", err)
 	}
-	if len(newUsers) == 0 {
-		delete(foundMsg.Reactions, emoji)
+	a.search.remove(messageID)
+	if parentChannel, parentID, ok := splitThreadChannel(channel); ok {
+		_, err := a.store.ModifyMessage(parentChannel, parentID, func(parent *Message) error {
+			if parent.ReplyCount > 0 {
+				parent.ReplyCount--
+			}
+			return nil
+		})
+		if err != nil && err != ErrNotFound {
+			log.Printf("Ошибка обновления счётчика ответов %s: %v", parentID, err)
+		}
 	} else {
-		foundMsg.Reactions[emoji] = newUsers
+		a.deleteThread(channel, messageID)
 	}
-	if err = UpdateMessage(channel, *foundMsg); err != nil {
-		return fmt.Errorf("не удалось обновить сообщение: %v", err)
+	if _, err := a.unpin(channel, messageID, username); err != nil {
+		log.Printf("Ошибка открепления удалённого сообщения %s: %v", messageID, err)
 	}
-	a.hub.BroadcastToChannel(channel, *foundMsg)
+	a.hub.BroadcastMessageDeleted(MessageDeleted{Channel: channel, MessageID: messageID, DeletedBy: username})
+	log.Printf("🗑️ %s удалил сообщение %s в #%s", username, messageID, channel)
 	return nil
 }
 
+// GetMessageRevisions возвращает прежние версии текста сообщения, от старых к новым
+func (a *App) GetMessageRevisions(messageID, channel, username string) ([]MessageRevision, error) {
+	if err := a.checkChannelAccess(channel, username); err != nil {
+		return nil, err
+	}
+	msg, err := a.store.GetMessage(channel, messageID)
+	if err == ErrNotFound {
+		return nil, fmt.Errorf("сообщение не найдено")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить сообщение: %v", err)
+	}
+	if msg.Revisions == nil {
+		return []MessageRevision{}, nil
+	}
+	return msg.Revisions, nil
+}
+
 func (a *App) CreateChannel(name, description, createdBy string) (Channel, error) {
+	return a.createChannel(name, description, createdBy, false, nil)
+}
+
+func (a *App) createChannel(name, description, createdBy string, private bool, members []string) (Channel, error) {
 	if name == "" {
 		return Channel{}, fmt.Errorf("имя канала не может быть пустым")
 	}
-	existingChannel, err := GetChannel(name)
+	if strings.Contains(name, ":") {
+		return Channel{}, fmt.Errorf("имя канала не может содержать ':'")
+	}
+	existingChannel, err := a.store.GetChannel(name)
 	if err == nil && existingChannel != nil {
 		return Channel{}, fmt.Errorf("канал #%s уже существует", name)
 	}
-	channel := Channel{ID: uuid.New().String(), Name: name, Description: description, Members: []string{createdBy}, CreatedBy: createdBy, CreatedAt: time.Now(), IsPrivate: false}
-	if err = SaveChannel(channel); err != nil {
+	channel := Channel{ID: uuid.New().String(), Name: name, Description: description, Members: normalizeMembers(append(members, createdBy)), CreatedBy: createdBy, CreatedAt: time.Now(), IsPrivate: private}
+	if err = a.store.SaveChannel(channel); err != nil {
 		return Channel{}, fmt.Errorf("не удалось создать канал: %v", err)
 	}
 	log.Printf("📢 Канал #%s создан пользователем %s", name, createdBy)
 	return channel, nil
 }
 
-func (a *App) GetChannels() ([]Channel, error) {
-	channels, err := GetAllChannels()
+// GetChannels возвращает публичные каналы и приватные каналы, где username участник
+func (a *App) GetChannels(username string) ([]Channel, error) {
+	channels, err := a.store.GetAllChannels()
 	if err != nil {
 		log.Printf("Ошибка получения каналов: %v", err)
 		return []Channel{}, nil
 	}
-	return channels, nil
+	result := make([]Channel, 0, len(channels))
+	for _, channel := range channels {
+		if channel.IsDirect {
+			continue
+		}
+		if channel.IsPrivate && !contains(channel.Members, username) {
+			continue
+		}
+		result = append(result, channel)
+	}
+	return result, nil
+}
+
+// checkChannelAccess проверяет, что пользователь может читать канал, писать
+// в него и подписываться на него: в приватные каналы и личные переписки -
+// только участники. Несуществующий канал недоступен никому: каналы
+// создаются только через CreateChannel, CreatePrivateChannel и
+// OpenDirectMessage.
+func (a *App) checkChannelAccess(channel, username string) error {
+	ch, err := a.store.GetChannel(rootChannel(channel))
+	if err == ErrNotFound {
+		return fmt.Errorf("канал не найден")
+	}
+	if err != nil {
+		return fmt.Errorf("не удалось получить канал: %v", err)
+	}
+	if ch.IsPrivate && !contains(ch.Members, username) {
+		return fmt.Errorf("нет доступа к каналу")
+	}
+	return nil
 }
 
 func (a *App) DeleteChannel(name, username string) error {
-	channel, err := GetChannel(name)
+	channel, err := a.store.GetChannel(name)
 	if err != nil {
 		return fmt.Errorf("канал не найден")
 	}
@@ -171,27 +353,59 @@ func (a *App) DeleteChannel(name, username string) error {
 	if systemChannels[name] {
 		return fmt.Errorf("нельзя удалить системный канал")
 	}
-	if err = DeleteChannel(name); err != nil {
+	if err = a.store.DeleteChannel(name); err != nil {
 		return fmt.Errorf("не удалось удалить канал: %v", err)
 	}
+	a.deleteChannelContents(name)
+	a.search.removeChannel(name)
 	log.Printf("🗑️ Канал #%s удален пользователем %s", name, username)
 	return nil
 }
 
+// deleteChannelContents удаляет историю удалённого канала вместе с тредами,
+// закреплённые сообщения и приглашения, чтобы канал, созданный заново под
+// тем же именем, не открыл новым участникам старую переписку
+func (a *App) deleteChannelContents(name string) {
+	if err := a.deleteAllMessages(name); err != nil {
+		log.Printf("Ошибка удаления сообщений #%s: %v", name, err)
+	}
+	if err := a.store.SavePins(name, nil); err != nil {
+		log.Printf("Ошибка удаления закреплённых сообщений #%s: %v", name, err)
+	}
+	for _, user := range userManager.GetAllUsers() {
+		if err := a.store.DeleteInvitation(name, user.Username); err != nil {
+			log.Printf("Ошибка удаления приглашения в #%s для %s: %v", name, user.Username, err)
+		}
+	}
+}
+
 func (a *App) JoinChannel(channelName, username string) error {
-	channel, err := GetChannel(channelName)
+	channel, err := a.store.GetChannel(channelName)
 	if err != nil {
 		return fmt.Errorf("канал не найден")
 	}
+	if channel.IsDirect {
+		return fmt.Errorf("нельзя присоединиться к чужой личной переписке")
+	}
 	for _, member := range channel.Members {
 		if member == username {
 			return nil
 		}
 	}
+	if channel.IsPrivate {
+		// В приватный канал можно войти только по приглашению
+		if !a.hasInvitation(channelName, username) {
+			return fmt.Errorf("в приватный канал можно войти только по приглашению")
+		}
+		if err := a.store.DeleteInvitation(channelName, username); err != nil {
+			log.Printf("Ошибка удаления приглашения в #%s для %s: %v", channelName, username, err)
+		}
+	}
 	channel.Members = append(channel.Members, username)
-	if err = SaveChannel(*channel); err != nil {
+	if err = a.store.SaveChannel(*channel); err != nil {
 		return fmt.Errorf("не удалось присоединиться к каналу: %v", err)
 	}
+	a.hub.AddChannelToClient(username, channelName)
 	log.Printf("✅ %s присоединился к #%s", username, channelName)
 	return nil
 }
@@ -0,0 +1,16 @@
+package main
+
+import "testing"
+
+// newTestApp собирает App на хранилище в памяти с системными каналами и
+// зарегистрированными users. Вложения пишутся во временный каталог теста.
+func newTestApp(t *testing.T, users ...string) *App {
+	t.Helper()
+	t.Setenv("GOTHERMO_BLOB_DIR", t.TempDir())
+	app := NewApp(NewMemoryStore())
+	app.initDefaultChannels()
+	for _, username := range users {
+		userManager.RegisterUser(username, username+"@example.com")
+	}
+	return app
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxAttachmentsPerMessage = 10
+	maxAttachmentNameLength  = 255
+)
+
+// attachmentName оставляет от имени файла только базовое имя без
+// управляющих символов
+func attachmentName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == "/" {
+		return "file"
+	}
+	if runes := []rune(name); len(runes) > maxAttachmentNameLength {
+		name = string(runes[:maxAttachmentNameLength])
+	}
+	return name
+}
+
+// storeAttachment сохраняет загруженный файл в хранилище вложений
+func (a *App) storeAttachment(username, name string, data []byte) (Attachment, error) {
+	if a.blobs == nil {
+		return Attachment{}, fmt.Errorf("хранилище вложений недоступно")
+	}
+
+	attachment, err := a.blobs.Put(data, username)
+	if err != nil {
+		return Attachment{}, err
+	}
+	attachment.Name = attachmentName(name)
+
+	log.Printf("📎 %s загрузил %s (%s, %d байт)", username, attachment.Name, attachment.Type, attachment.Size)
+	return attachment, nil
+}
+
+// UploadAttachment загружает файл, переданный из фронтенда в base64.
+// Возвращённое вложение передаётся в SendMessageWithAttachments.
+func (a *App) UploadAttachment(username, name, data string) (Attachment, error) {
+	if _, exists := userManager.GetUser(username); !exists {
+		return Attachment{}, fmt.Errorf("пользователь %s не найден", username)
+	}
+	if int64(base64.StdEncoding.DecodedLen(len(data))) > maxAttachmentSize()+3 {
+		return Attachment{}, fmt.Errorf("файл больше %d МБ", maxAttachmentSize()>>20)
+	}
+
+	content, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return Attachment{}, fmt.Errorf("неверное содержимое файла: %v", err)
+	}
+	return a.storeAttachment(username, name, content)
+}
+
+// resolveAttachments сверяет вложения из запроса клиента с хранилищем:
+// от клиента берётся только хеш и имя, остальное - из метаданных блоба.
+// Прикрепить можно только файлы, которые username загрузил сам.
+func (a *App) resolveAttachments(username string, requested []Attachment) ([]Attachment, error) {
+	if len(requested) == 0 {
+		return nil, nil
+	}
+	if len(requested) > maxAttachmentsPerMessage {
+		return nil, fmt.Errorf("к сообщению можно прикрепить не больше %d файлов", maxAttachmentsPerMessage)
+	}
+	if a.blobs == nil {
+		return nil, fmt.Errorf("хранилище вложений недоступно")
+	}
+
+	attachments := make([]Attachment, 0, len(requested))
+	for _, req := range requested {
+		attachment, err := a.blobs.UploadedInfo(req.Hash, username)
+		if err == ErrNotFound {
+			return nil, fmt.Errorf("вложение %s не найдено, загрузите файл заново", attachmentName(req.Name))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("не удалось получить вложение: %v", err)
+		}
+		attachment.Name = attachmentName(req.Name)
+		attachments = append(attachments, attachment)
+	}
+	return attachments, nil
+}
+
+// SendMessageWithAttachments отправляет сообщение с вложениями,
+// загруженными через UploadAttachment или POST /attachments. Текст
+// может быть пустым, если есть хотя бы одно вложение.
+func (a *App) SendMessageWithAttachments(user, text, channel string, attachments []Attachment) (string, error) {
+	if text == "" && len(attachments) == 0 {
+		return "", fmt.Errorf("сообщение не может быть пустым")
+	}
+	if err := a.checkChannelAccess(channel, user); err != nil {
+		return "", err
+	}
+	resolved, err := a.resolveAttachments(user, attachments)
+	if err != nil {
+		return "", err
+	}
+
+	msg := Message{ID: uuid.New().String(), User: user, Text: text, Channel: channel, Timestamp: time.Now(), Reactions: make(map[string][]string), Attachments: resolved}
+	a.resolveMentions(&msg)
+	if err := a.store.SaveMessage(msg); err != nil {
+		return "", fmt.Errorf("не удалось сохранить сообщение: %v", err)
+	}
+	a.search.add(msg)
+	a.hub.BroadcastToChannel(channel, msg)
+	a.notifyMentions(msg)
+	a.notifyDesktop(msg)
+	if len(resolved) > 0 {
+		log.Printf("📨 %s -> #%s: %s (вложений: %d)", user, channel, truncate(text, 50), len(resolved))
+	} else {
+		log.Printf("📨 %s -> #%s: %s", user, channel, truncate(text, 50))
+	}
+	return msg.ID, nil
+}
+
+// writeJSON отвечает JSON с кодом code
+func writeJSON(w http.ResponseWriter, code int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeJSONError(w http.ResponseWriter, code int, message string) {
+	writeJSON(w, code, map[string]string{"error": message})
+}
+
+// authenticateRequest проверяет access-токен запроса (заголовок Bearer
+// или параметр token) и возвращает имя пользователя
+func authenticateRequest(r *http.Request) (string, error) {
+	token := tokenFromRequest(r)
+	if token == "" {
+		return "", fmt.Errorf("требуется авторизация")
+	}
+	user, _, err := userManager.Authenticate(token)
+	if err != nil {
+		return "", err
+	}
+	return user.Username, nil
+}
+
+// handleUpload принимает файл в поле file формы multipart/form-data
+func (a *App) handleUpload(w http.ResponseWriter, r *http.Request) {
+	username, err := authenticateRequest(r)
+	if err != nil {
+		writeJSONError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	// запас на заголовки multipart поверх самого файла
+	r.Body = http.MaxBytesReader(w, r.Body, maxAttachmentSize()+1<<20)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("не удалось прочитать файл: %v", err))
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxAttachmentSize()+1))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("не удалось прочитать файл: %v", err))
+		return
+	}
+
+	attachment, err := a.storeAttachment(username, header.Filename, data)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, attachment)
+}
+
+// handleDownload отдаёт вложение или его миниатюру:
+// GET /attachments/{hash}?channel=...&message=...
+// Файл отдаётся только тем, кто видит канал, и только если он прикреплён
+// к указанному сообщению - знать хеш недостаточно.
+func (a *App) handleDownload(w http.ResponseWriter, r *http.Request) {
+	username, err := authenticateRequest(r)
+	if err != nil {
+		writeJSONError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	hash := r.PathValue("hash")
+	channel := r.URL.Query().Get("channel")
+	messageID := r.URL.Query().Get("message")
+	if err := a.checkChannelAccess(channel, username); err != nil {
+		writeJSONError(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	msg, err := a.store.GetMessage(channel, messageID)
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, "сообщение не найдено")
+		return
+	}
+	var attachment *Attachment
+	for i := range msg.Attachments {
+		if msg.Attachments[i].Hash == hash || msg.Attachments[i].ThumbnailHash == hash {
+			attachment = &msg.Attachments[i]
+			break
+		}
+	}
+	if attachment == nil || a.blobs == nil {
+		writeJSONError(w, http.StatusNotFound, "вложение не найдено")
+		return
+	}
+
+	info, err := a.blobs.Info(hash)
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, "вложение не найдено")
+		return
+	}
+	file, err := a.blobs.Open(hash)
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, "вложение не найдено")
+		return
+	}
+	defer file.Close()
+
+	disposition := "attachment"
+	if strings.HasPrefix(info.Type, "image/") {
+		disposition = "inline"
+	}
+	w.Header().Set("Content-Type", info.Type)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": attachment.Name}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	// содержимое по хешу не меняется, но доступ проверяется на каждый запрос
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	http.ServeContent(w, r, "", time.Time{}, file)
+}
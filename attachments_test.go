@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestAttachmentName(t *testing.T) {
+	for name, want := range map[string]string{
+		"отчёт.pdf":           "отчёт.pdf",
+		"../../etc/passwd":    "passwd",
+		`C:\Users\al\a.txt`:   "a.txt",
+		"плохое\x00\nимя.txt": "плохоеимя.txt",
+		"  ":                  "file",
+		"/":                   "file",
+	} {
+		if got := attachmentName(name); got != want {
+			t.Errorf("attachmentName(%q) = %q, ожидалось %q", name, got, want)
+		}
+	}
+}
+
+// Прикрепить можно только файл, загруженный самим отправителем, даже
+// если хеш чужого файла известен
+func TestAttachmentOwnership(t *testing.T) {
+	app := newTestApp(t, "al", "eve")
+	data := base64.StdEncoding.EncodeToString([]byte("секретный отчёт"))
+	uploaded, err := app.UploadAttachment("al", "отчёт.txt", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stolen := []Attachment{{Hash: uploaded.Hash, Name: "мой.txt"}}
+	if _, err := app.SendMessageWithAttachments("eve", "", "general", stolen); err == nil {
+		t.Error("прикреплён чужой файл")
+	}
+
+	// поля, кроме хеша и имени, берутся из хранилища, а не от клиента
+	forged := []Attachment{{Hash: uploaded.Hash, Name: "отчёт.txt", Size: 1, Type: "image/png"}}
+	id, err := app.SendMessageWithAttachments("al", "", "general", forged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, _ := app.store.GetMessage("general", id)
+	if len(msg.Attachments) != 1 || msg.Attachments[0].Size != uploaded.Size || msg.Attachments[0].Type != uploaded.Type {
+		t.Errorf("вложение %+v, ожидалось %+v", msg.Attachments, uploaded)
+	}
+
+	// тот же файл, загруженный вторым пользователем, можно прикрепить и ему
+	if _, err := app.UploadAttachment("eve", "копия.txt", data); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.SendMessageWithAttachments("eve", "", "general", stolen); err != nil {
+		t.Errorf("свой файл не прикрепился: %v", err)
+	}
+
+	if _, err := app.SendMessageWithAttachments("al", "", "general", []Attachment{{Hash: "../../etc/passwd"}}); err == nil {
+		t.Error("прикреплено вложение с неверным хешем")
+	}
+	if _, err := app.UploadAttachment("nobody", "a.txt", data); err == nil {
+		t.Error("загрузка от несуществующего пользователя")
+	}
+}
@@ -55,7 +55,7 @@ func (a *App) Register(email, password string) (User, error) {
 	username := strings.Split(email, "@")[0]
 
 	// Проверяем, существует ли пользователь
-	if _, err := GetUserFromRedis(email); err == nil {
+	if _, err := a.store.GetUser(email); err == nil {
 		log.Printf("❌ Пользователь уже существует: %s", email)
 		return User{}, fmt.Errorf("пользователь с таким email уже существует")
 	}
@@ -71,38 +71,45 @@ func (a *App) Register(email, password string) (User, error) {
 	user := userManager.RegisterUser(username, email)
 
 	// ✅ ВАЖНО: Сначала сохраняем пользователя
-	if err := SaveUserToRedis(user); err != nil {
-		log.Printf("❌ Ошибка сохранения пользователя в Redis: %v", err)
+	if err := a.store.SaveUser(user); err != nil {
+		log.Printf("❌ Ошибка сохранения пользователя: %v", err)
 		return User{}, fmt.Errorf("ошибка сохранения пользователя: %v", err)
 	}
-	log.Printf("💾 Пользователь сохранен в Redis: %s", email)
+	log.Printf("💾 Пользователь сохранен: %s", email)
 
 	// ✅ ВАЖНО: Затем сохраняем пароль
-	if err := SaveUserPasswordToRedis(email, hashedPassword); err != nil {
-		log.Printf("❌ Ошибка сохранения пароля в Redis: %v", err)
+	if err := a.store.SaveUserPassword(email, hashedPassword); err != nil {
+		log.Printf("❌ Ошибка сохранения пароля: %v", err)
 		return User{}, fmt.Errorf("ошибка сохранения пароля: %v", err)
 	}
-	log.Printf("🔐 Пароль сохранен в Redis для: %s", email)
+	log.Printf("🔐 Пароль сохранен для: %s", email)
 
 	// Проверяем что всё сохранилось
-	savedUser, _ := GetUserFromRedis(email)
+	savedUser, _ := a.store.GetUser(email)
 	if savedUser != nil {
-		log.Printf("✅ Проверка: пользователь найден в Redis: %s", savedUser.Username)
+		log.Printf("✅ Проверка: пользователь найден в хранилище: %s", savedUser.Username)
 	}
 
-	savedPass, _ := GetUserPasswordFromRedis(email)
+	savedPass, _ := a.store.GetUserPassword(email)
 	if savedPass != "" {
-		log.Printf("✅ Проверка: пароль найден в Redis для: %s", email)
+		log.Printf("✅ Проверка: пароль найден в хранилище для: %s", email)
 	}
 
 	log.Printf("✅ Пользователь зарегистрирован: %s (ID: %s)", username, user.ID)
 
+	tokens, err := userManager.CreateSession(user)
+	if err != nil {
+		return User{}, err
+	}
+
+	a.notifications.setLocalUser(username)
+
 	// Broadcast статуса "online"
 	if globalHub != nil {
 		globalHub.BroadcastStatusUpdate(username, "online")
 	}
 
-	return *user, nil
+	return withTokens(user, tokens), nil
 }
 
 // Login выполняет вход с проверкой пароля
@@ -119,22 +126,22 @@ func (a *App) Login(email, password string) (User, error) {
 	}
 
 	// 1. Проверяем пользователя
-	userFromRedis, err := GetUserFromRedis(email)
+	storedUser, err := a.store.GetUser(email)
 	if err != nil {
-		log.Printf("❌ Пользователь не найден в Redis: %s", email)
+		log.Printf("❌ Пользователь не найден в хранилище: %s", email)
 		return User{}, fmt.Errorf("пользователь не найден")
 	}
-	log.Printf("✅ Найден пользователь: %s", userFromRedis.Username)
+	log.Printf("✅ Найден пользователь: %s", storedUser.Username)
 
 	// 2. Проверяем пароль
-	savedHash, err := GetUserPasswordFromRedis(email)
+	savedHash, err := a.store.GetUserPassword(email)
 	if err != nil {
 		log.Printf("❌ Пароль не найден для: %s", email)
 		// Попробуем создать пароль заново для этого пользователя (временно)
 		log.Printf("🔄 Восстанавливаем пароль для: %s", email)
 		hashedPassword, _ := HashPassword(password)
-		SaveUserPasswordToRedis(email, hashedPassword)
-		savedHash, _ = GetUserPasswordFromRedis(email)
+		a.store.SaveUserPassword(email, hashedPassword)
+		savedHash, _ = a.store.GetUserPassword(email)
 	}
 
 	// 3. Проверяем пароль
@@ -146,11 +153,17 @@ func (a *App) Login(email, password string) (User, error) {
 	username := strings.Split(email, "@")[0]
 	user := userManager.RegisterUser(username, email)
 
+	tokens, err := userManager.CreateSession(user)
+	if err != nil {
+		return User{}, err
+	}
+
 	log.Printf("✅ Пользователь вошёл: %s", username)
+	a.notifications.setLocalUser(username)
 
 	if globalHub != nil {
 		globalHub.BroadcastStatusUpdate(username, "online")
 	}
 
-	return *user, nil
+	return withTokens(user, tokens), nil
 }
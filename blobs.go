@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultMaxAttachmentMB = 10
+	thumbnailSize          = 320      // длинная сторона миниатюры в пикселях
+	maxThumbnailPixels     = 40 << 20 // больше не декодируем: защита от "бомб"
+	blobMetaSuffix         = ".json"  // рядом с блобом лежат его метаданные
+	blobDirPerm            = 0o755
+	blobFilePerm           = 0o644
+)
+
+// allowedAttachmentTypes - MIME-типы, которые можно загружать. Тип
+// определяется по содержимому, а не по расширению или заголовкам клиента.
+var allowedAttachmentTypes = []string{
+	"image/png",
+	"image/jpeg",
+	"image/gif",
+	"image/webp",
+	"text/plain",
+	"application/pdf",
+	"application/zip",
+	"application/x-gzip",
+}
+
+// maxAttachmentSize читает лимит размера вложения из GOTHERMO_MAX_ATTACHMENT_MB
+func maxAttachmentSize() int64 {
+	mb, err := strconv.Atoi(getEnv("GOTHERMO_MAX_ATTACHMENT_MB", strconv.Itoa(defaultMaxAttachmentMB)))
+	if err != nil || mb <= 0 {
+		mb = defaultMaxAttachmentMB
+	}
+	return int64(mb) << 20
+}
+
+// BlobStore хранит файлы на локальном диске по SHA-256 содержимого:
+// одинаковые файлы занимают место один раз. Блоб лежит в <root>/<ab>/<hash>,
+// его метаданные - в <hash>.json.
+type BlobStore struct {
+	root    string
+	maxSize int64
+	mu      sync.Mutex // сериализует обновление метаданных
+}
+
+// blobMeta - содержимое <hash>.json: метаданные вложения и кто загружал
+// файл. Прикрепить блоб к сообщению может только загрузивший его, поэтому
+// хеша, увиденного в чужом канале, недостаточно.
+type blobMeta struct {
+	Attachment
+	Uploaders []string `json:"uploaders,omitempty"`
+}
+
+func NewBlobStore(root string, maxSize int64) (*BlobStore, error) {
+	if err := os.MkdirAll(root, blobDirPerm); err != nil {
+		return nil, fmt.Errorf("не удалось создать каталог вложений: %v", err)
+	}
+	return &BlobStore{root: root, maxSize: maxSize}, nil
+}
+
+// NewBlobStoreFromEnv открывает хранилище вложений в GOTHERMO_BLOB_DIR
+func NewBlobStoreFromEnv() (*BlobStore, error) {
+	return NewBlobStore(getEnv("GOTHERMO_BLOB_DIR", "blobs"), maxAttachmentSize())
+}
+
+// validBlobHash проверяет, что hash - hex SHA-256. Это же не даёт выйти
+// из каталога хранилища через имя файла.
+func validBlobHash(hash string) bool {
+	if len(hash) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil && strings.ToLower(hash) == hash
+}
+
+func (b *BlobStore) path(hash string) string {
+	return filepath.Join(b.root, hash[:2], hash)
+}
+
+// detectType определяет MIME-тип по первым байтам содержимого
+func detectType(data []byte) string {
+	contentType := http.DetectContentType(data)
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return strings.TrimSpace(contentType)
+}
+
+// Put проверяет размер и тип файла, сохраняет его и миниатюру (для картинок),
+// запоминает загрузившего и возвращает метаданные без имени файла
+func (b *BlobStore) Put(data []byte, uploader string) (Attachment, error) {
+	if len(data) == 0 {
+		return Attachment{}, fmt.Errorf("файл пустой")
+	}
+	if int64(len(data)) > b.maxSize {
+		return Attachment{}, fmt.Errorf("файл больше %d МБ", b.maxSize>>20)
+	}
+	contentType := detectType(data)
+	if !contains(allowedAttachmentTypes, contentType) {
+		return Attachment{}, fmt.Errorf("тип файла %s не поддерживается", contentType)
+	}
+
+	info := Attachment{Size: int64(len(data)), Type: contentType}
+	if thumbnail, width, height, ok := makeThumbnail(data, contentType); ok {
+		info.Width, info.Height = width, height
+		if thumbnail != nil {
+			thumbHash, err := b.write(thumbnail, Attachment{Size: int64(len(thumbnail)), Type: detectType(thumbnail)}, "")
+			if err != nil {
+				return Attachment{}, err
+			}
+			info.ThumbnailHash = thumbHash
+		}
+	}
+
+	hash, err := b.write(data, info, uploader)
+	if err != nil {
+		return Attachment{}, err
+	}
+	info.Hash = hash
+	return info, nil
+}
+
+// write сохраняет содержимое и метаданные, если такого блоба ещё нет, и
+// добавляет uploader к загрузившим (пустой - миниатюра, её не прикрепляют).
+// Файл сначала пишется во временный и переименовывается, чтобы читатели
+// не увидели его недописанным.
+func (b *BlobStore) write(data []byte, info Attachment, uploader string) (string, error) {
+	sum := sha256.Sum256(data)
+	info.Hash = hex.EncodeToString(sum[:])
+	path := b.path(info.Hash)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	meta, err := b.readMeta(info.Hash)
+	if err == nil {
+		if uploader == "" || contains(meta.Uploaders, uploader) {
+			return info.Hash, nil
+		}
+		meta.Uploaders = append(meta.Uploaders, uploader)
+		if err := b.writeMeta(meta); err != nil {
+			return "", fmt.Errorf("не удалось сохранить файл: %v", err)
+		}
+		return info.Hash, nil
+	}
+	if err != ErrNotFound {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), blobDirPerm); err != nil {
+		return "", fmt.Errorf("не удалось сохранить файл: %v", err)
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return "", fmt.Errorf("не удалось сохранить файл: %v", err)
+	}
+
+	meta = blobMeta{Attachment: info}
+	if uploader != "" {
+		meta.Uploaders = []string{uploader}
+	}
+	if err := b.writeMeta(meta); err != nil {
+		return "", fmt.Errorf("не удалось сохранить файл: %v", err)
+	}
+	return info.Hash, nil
+}
+
+func (b *BlobStore) writeMeta(meta blobMeta) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(b.path(meta.Hash)+blobMetaSuffix, data)
+}
+
+// readMeta читает <hash>.json или возвращает ErrNotFound
+func (b *BlobStore) readMeta(hash string) (blobMeta, error) {
+	if !validBlobHash(hash) {
+		return blobMeta{}, ErrNotFound
+	}
+	data, err := os.ReadFile(b.path(hash) + blobMetaSuffix)
+	if os.IsNotExist(err) {
+		return blobMeta{}, ErrNotFound
+	}
+	if err != nil {
+		return blobMeta{}, err
+	}
+
+	var meta blobMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return blobMeta{}, err
+	}
+	return meta, nil
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), blobFilePerm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Info возвращает метаданные блоба или ErrNotFound
+func (b *BlobStore) Info(hash string) (Attachment, error) {
+	meta, err := b.readMeta(hash)
+	if err != nil {
+		return Attachment{}, err
+	}
+	return meta.Attachment, nil
+}
+
+// UploadedInfo возвращает метаданные блоба, если username загружал его сам,
+// иначе ErrNotFound - не сообщаем, что чужой файл с таким хешем существует
+func (b *BlobStore) UploadedInfo(hash, username string) (Attachment, error) {
+	meta, err := b.readMeta(hash)
+	if err != nil {
+		return Attachment{}, err
+	}
+	if !contains(meta.Uploaders, username) {
+		return Attachment{}, ErrNotFound
+	}
+	return meta.Attachment, nil
+}
+
+// Open открывает содержимое блоба на чтение
+func (b *BlobStore) Open(hash string) (*os.File, error) {
+	if !validBlobHash(hash) {
+		return nil, ErrNotFound
+	}
+	file, err := os.Open(b.path(hash))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+// makeThumbnail возвращает размеры картинки и её уменьшенную копию.
+// Миниатюра nil, если картинка и так не больше thumbnailSize. ok == false -
+// не картинка или формат, который не умеем декодировать (webp).
+func makeThumbnail(data []byte, contentType string) (thumbnail []byte, width, height int, ok bool) {
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, 0, 0, false
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, 0, false
+	}
+	width, height = config.Width, config.Height
+	if width <= thumbnailSize && height <= thumbnailSize {
+		return nil, width, height, true
+	}
+	if width*height > maxThumbnailPixels {
+		return nil, width, height, true
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, width, height, true
+	}
+	thumb := scaleDown(src, thumbnailSize)
+
+	var buf bytes.Buffer
+	if contentType == "image/jpeg" {
+		err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 80})
+	} else {
+		err = png.Encode(&buf, thumb)
+	}
+	if err != nil {
+		return nil, width, height, true
+	}
+	return buf.Bytes(), width, height, true
+}
+
+// scaleDown уменьшает картинку так, чтобы длинная сторона стала maxSide,
+// усредняя исходные пиксели, попавшие в каждый пиксель результата
+func scaleDown(src image.Image, maxSide int) *image.NRGBA {
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	dstW, dstH := maxSide, maxSide
+	if srcW > srcH {
+		dstH = max(1, srcH*maxSide/srcW)
+	} else {
+		dstW = max(1, srcW*maxSide/srcH)
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0, y1 := y*srcH/dstH, max((y+1)*srcH/dstH, y*srcH/dstH+1)
+		for x := 0; x < dstW; x++ {
+			x0, x1 := x*srcW/dstW, max((x+1)*srcW/dstW, x*srcW/dstW+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := color.NRGBAModel.Convert(src.At(bounds.Min.X+sx, bounds.Min.Y+sy)).(color.NRGBA)
+					r, g, b, a = r+uint64(c.R), g+uint64(c.G), b+uint64(c.B), a+uint64(c.A)
+					n++
+				}
+			}
+			dst.SetNRGBA(x, y, color.NRGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: uint8(a / n)})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"log"
+	"os"
+	"time"
+)
+
+const defaultCompactionInterval = 10 * time.Minute
+
+// compactionInterval читает период фонового уплотнения из GOTHERMO_COMPACTION_INTERVAL
+// (формат time.ParseDuration, например "5m")
+func compactionInterval() time.Duration {
+	if value := os.Getenv("GOTHERMO_COMPACTION_INTERVAL"); value != "" {
+		if interval, err := time.ParseDuration(value); err == nil && interval > 0 {
+			return interval
+		}
+		log.Printf("Неверный GOTHERMO_COMPACTION_INTERVAL=%q, используется %v", value, defaultCompactionInterval)
+	}
+	return defaultCompactionInterval
+}
+
+// runCompaction периодически убирает надгробия удалённых сообщений
+// из каналов и тредов, пока приложение не завершится
+func (a *App) runCompaction(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			a.compactAllChannels()
+		case <-a.quit:
+			return
+		}
+	}
+}
+
+func (a *App) compactAllChannels() {
+	channels, err := a.store.PendingCompaction()
+	if err != nil {
+		log.Printf("Ошибка получения каналов для уплотнения: %v", err)
+		return
+	}
+
+	for _, channel := range channels {
+		removed, err := a.store.CompactMessages(channel)
+		if err != nil {
+			log.Printf("Ошибка уплотнения #%s: %v", channel, err)
+			continue
+		}
+		if removed > 0 {
+			log.Printf("🧹 #%s: убрано %d удалённых сообщений", channel, removed)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	directChannelPrefix = "dm:"
+	maxDirectMembers    = 9
+)
+
+// directChannelName строит имя личной переписки из отсортированного списка
+// участников, поэтому один и тот же состав всегда попадает в одну переписку
+func directChannelName(members []string) string {
+	return directChannelPrefix + strings.Join(members, ",")
+}
+
+// normalizeMembers убирает пустые имена и дубликаты и сортирует участников
+func normalizeMembers(usernames []string) []string {
+	members := []string{}
+	for _, username := range usernames {
+		username = strings.TrimSpace(username)
+		if username != "" && !contains(members, username) {
+			members = append(members, username)
+		}
+	}
+	sort.Strings(members)
+	return members
+}
+
+// OpenDirectMessage открывает личную переписку username с participants
+// (один собеседник - 1:1, несколько - групповая). Если переписка с таким
+// составом уже есть, возвращается она.
+func (a *App) OpenDirectMessage(username string, participants []string) (Channel, error) {
+	members := normalizeMembers(append(participants, username))
+	if len(members) < 2 {
+		return Channel{}, fmt.Errorf("выберите хотя бы одного собеседника")
+	}
+	if len(members) > maxDirectMembers {
+		return Channel{}, fmt.Errorf("в личной переписке может быть не больше %d участников", maxDirectMembers)
+	}
+	for _, member := range members {
+		if _, exists := userManager.GetUser(member); !exists {
+			return Channel{}, fmt.Errorf("пользователь %s не найден", member)
+		}
+	}
+
+	name := directChannelName(members)
+	if existing, err := a.store.GetChannel(name); err == nil {
+		return *existing, nil
+	}
+
+	channel := Channel{ID: uuid.New().String(), Name: name, Description: "", Members: members, CreatedBy: username, CreatedAt: time.Now(), IsPrivate: true, IsDirect: true}
+	if err := a.store.SaveChannel(channel); err != nil {
+		return Channel{}, fmt.Errorf("не удалось создать переписку: %v", err)
+	}
+
+	a.hub.BroadcastDirectMessageOpened(channel)
+	log.Printf("💬 %s открыл личную переписку: %s", username, strings.Join(members, ", "))
+	return channel, nil
+}
+
+// GetDirectMessages возвращает личные переписки пользователя
+func (a *App) GetDirectMessages(username string) ([]Channel, error) {
+	channels, err := a.store.GetAllChannels()
+	if err != nil {
+		log.Printf("Ошибка получения каналов: %v", err)
+		return []Channel{}, nil
+	}
+
+	result := []Channel{}
+	for _, channel := range channels {
+		if channel.IsDirect && contains(channel.Members, username) {
+			result = append(result, channel)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+	return result, nil
+}
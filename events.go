@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// replayBufferSize - сколько последних событий каждого потока хранится
+	// для досылки после переподключения
+	replayBufferSize = 256
+
+	// presenceStream - поток событий статусов. Двоеточие не допускается
+	// в именах каналов, поэтому поток не пересечётся с каналом.
+	presenceStream = ":presence"
+
+	// maxResumeStreams ограничивает число потоков в resume: список присылает
+	// клиент, и досылка идёт под мьютексом журнала событий
+	maxResumeStreams = 512
+)
+
+// ResumeState - что клиент уже получил до обрыва соединения: эпоха хаба
+// и последний номер события по каждому потоку (каналу или presenceStream)
+type ResumeState struct {
+	Epoch   string            `json:"epoch"`
+	Streams map[string]uint64 `json:"streams"`
+}
+
+// ResyncRequired сообщает, какие потоки нельзя дослать и нужно перечитать
+// целиком. All - перечитать всё: сервер перезапустился или resume слишком велик.
+type ResyncRequired struct {
+	Streams []string `json:"streams"`
+	All     bool     `json:"all,omitempty"`
+}
+
+// Replay - все пропущенные события, одним сообщением на подключение
+type Replay struct {
+	Streams []StreamReplay `json:"streams"`
+}
+
+// StreamReplay - пропущенные события одного потока в порядке номеров
+type StreamReplay struct {
+	Stream string            `json:"stream"`
+	Events []json.RawMessage `json:"events"`
+}
+
+// hubEvent - разосланное событие вместе с тем, кому оно предназначалось,
+// чтобы при досылке заново проверить доступ
+type hubEvent struct {
+	seq     uint64
+	channel string
+	extra   []string
+	data    []byte
+}
+
+type eventStream struct {
+	seq    uint64
+	events []hubEvent
+}
+
+// eventLog нумерует события хаба и хранит хвост каждого потока.
+// Мьютекс держится и на время доставки, чтобы клиенты получали события
+// потока строго по возрастанию номеров.
+type eventLog struct {
+	epoch   string // меняется при перезапуске, номера начинаются заново
+	streams map[string]*eventStream
+	mu      sync.Mutex
+}
+
+func newEventLog() *eventLog {
+	return &eventLog{
+		epoch:   uuid.New().String(),
+		streams: make(map[string]*eventStream),
+	}
+}
+
+// append присваивает событию следующий номер потока, запоминает его и
+// возвращает сериализованное событие. Вызывается под l.mu.
+func (l *eventLog) append(stream, channel string, extra []string, wsMsg WSMessage) ([]byte, error) {
+	s, exists := l.streams[stream]
+	if !exists {
+		s = &eventStream{}
+		l.streams[stream] = s
+	}
+
+	wsMsg.Stream = stream
+	wsMsg.Seq = s.seq + 1
+	data, err := json.Marshal(wsMsg)
+	if err != nil {
+		return nil, err
+	}
+
+	s.seq++
+	s.events = append(s.events, hubEvent{seq: s.seq, channel: channel, extra: extra, data: data})
+	if len(s.events) > replayBufferSize {
+		s.events = append([]hubEvent(nil), s.events[len(s.events)-replayBufferSize:]...)
+	}
+	return data, nil
+}
+
+// missed возвращает события потока после lastSeq. ok == false, если часть
+// из них уже вытеснена из буфера. Вызывается под l.mu.
+func (l *eventLog) missed(stream string, lastSeq uint64) (events []hubEvent, ok bool) {
+	s, exists := l.streams[stream]
+	if !exists {
+		return nil, lastSeq == 0
+	}
+	if lastSeq > s.seq {
+		return nil, false
+	}
+	if lastSeq == s.seq {
+		return nil, true
+	}
+	if len(s.events) == 0 || s.events[0].seq > lastSeq+1 {
+		return nil, false
+	}
+
+	start := lastSeq + 1 - s.events[0].seq
+	return s.events[start:], true
+}
+
+// sendPresence рассылает событие статуса всем, кроме exclude
+func (h *Hub) sendPresence(wsMsg WSMessage, exclude string) int {
+	h.events.mu.Lock()
+	defer h.events.mu.Unlock()
+
+	data, err := h.events.append(presenceStream, "", nil, wsMsg)
+	if err != nil {
+		log.Printf("Ошибка маршалинга события %s: %v", wsMsg.Type, err)
+		return 0
+	}
+
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	sentCount := 0
+	for username, connections := range h.clients {
+		if username == exclude {
+			continue
+		}
+		for client := range connections {
+			if client.deliver(data) {
+				sentCount++
+			}
+		}
+	}
+	return sentCount
+}
+
+// replayTo досылает клиенту события, пропущенные до переподключения: не
+// больше одного replay и одного resync_required, чтобы не переполнить Send.
+// Потоки каналов, которые клиенту не видны или не существуют, пропускаются.
+// Вызывается под h.events.mu, поэтому новые события придут строго после досылки.
+func (h *Hub) replayTo(client *Client, resume *ResumeState) {
+	if resume == nil || len(resume.Streams) == 0 {
+		return
+	}
+	if resume.Epoch != h.events.epoch || len(resume.Streams) > maxResumeStreams {
+		client.sendEvent(WSMessage{Type: "resync_required", Payload: ResyncRequired{Streams: []string{}, All: true}})
+		return
+	}
+
+	channels, err := h.store.GetAllChannels()
+	if err != nil {
+		log.Printf("Ошибка получения каналов для досылки: %v", err)
+		client.sendEvent(WSMessage{Type: "resync_required", Payload: ResyncRequired{Streams: []string{}, All: true}})
+		return
+	}
+	byName := make(map[string]*Channel, len(channels))
+	for i := range channels {
+		byName[channels[i].Name] = &channels[i]
+	}
+
+	replay := Replay{Streams: []StreamReplay{}}
+	resync := []string{}
+	replayed := 0
+	for stream, lastSeq := range resume.Streams {
+		ch := byName[stream]
+		if stream != presenceStream && (ch == nil || ch.IsPrivate && !contains(ch.Members, client.Username)) {
+			continue
+		}
+
+		events, ok := h.events.missed(stream, lastSeq)
+		if !ok {
+			resync = append(resync, stream)
+			continue
+		}
+		if len(events) == 0 {
+			continue
+		}
+
+		streamReplay := StreamReplay{Stream: stream, Events: []json.RawMessage{}}
+		for _, event := range events {
+			if stream == presenceStream || client.canReceive(ch, event.channel, stream, event.extra) {
+				streamReplay.Events = append(streamReplay.Events, event.data)
+			}
+		}
+		replay.Streams = append(replay.Streams, streamReplay)
+		replayed += len(streamReplay.Events)
+	}
+
+	if len(replay.Streams) > 0 {
+		client.sendEvent(WSMessage{Type: "replay", Payload: replay})
+		log.Printf("🔁 %s: дослано %d событий из %d потоков", client.Username, replayed, len(replay.Streams))
+	}
+	if len(resync) > 0 {
+		client.sendEvent(WSMessage{Type: "resync_required", Payload: ResyncRequired{Streams: resync}})
+	}
+}
+
+// sendEvent отправляет событие одному подключению, не блокируя вызывающего.
+// Вызывается и из readPump, поэтому сначала проверяет, что хаб ещё не
+// отключил клиента и не закрыл его Send. Нельзя вызывать под h.mutex.
+func (c *Client) sendEvent(wsMsg WSMessage) {
+	data, err := json.Marshal(wsMsg)
+	if err != nil {
+		log.Printf("Ошибка маршалинга события %s: %v", wsMsg.Type, err)
+		return
+	}
+
+	c.Hub.mutex.RLock()
+	defer c.Hub.mutex.RUnlock()
+	if c.Hub.clients[c.Username][c] {
+		c.deliver(data)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func appendEvents(t *testing.T, l *eventLog, stream string, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if _, err := l.append(stream, stream, nil, WSMessage{Type: "channel_message"}); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+}
+
+func TestEventLogAppend(t *testing.T) {
+	l := newEventLog()
+	appendEvents(t, l, "general", 2)
+
+	data, err := l.append("general", "general", nil, WSMessage{Type: "channel_message"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg WSMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Stream != "general" || msg.Seq != 3 {
+		t.Errorf("событие %s #%d, ожидалось general #3", msg.Stream, msg.Seq)
+	}
+
+	// номера у каждого потока свои
+	data, _ = l.append("random", "random", nil, WSMessage{Type: "channel_message"})
+	json.Unmarshal(data, &msg)
+	if msg.Seq != 1 {
+		t.Errorf("первое событие random получило номер %d", msg.Seq)
+	}
+}
+
+func TestEventLogMissed(t *testing.T) {
+	l := newEventLog()
+	appendEvents(t, l, "general", 3)
+	appendEvents(t, l, "busy", replayBufferSize+5)
+
+	tests := []struct {
+		name     string
+		stream   string
+		lastSeq  uint64
+		wantSeqs []uint64
+		wantOK   bool
+	}{
+		{"все события", "general", 0, []uint64{1, 2, 3}, true},
+		{"пропущено одно", "general", 2, []uint64{3}, true},
+		{"ничего не пропущено", "general", 3, nil, true},
+		{"номер из будущего", "general", 5, nil, false},
+		{"пустой поток с нуля", "empty", 0, nil, true},
+		{"пустой поток с номером", "empty", 2, nil, false},
+		{"вытеснено из буфера", "busy", 1, nil, false},
+		{"хвост буфера", "busy", replayBufferSize + 3, []uint64{replayBufferSize + 4, replayBufferSize + 5}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, ok := l.missed(tt.stream, tt.lastSeq)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, ожидалось %v", ok, tt.wantOK)
+			}
+			if len(events) != len(tt.wantSeqs) {
+				t.Fatalf("досылается %d событий, ожидалось %d", len(events), len(tt.wantSeqs))
+			}
+			for i, event := range events {
+				if event.seq != tt.wantSeqs[i] {
+					t.Errorf("событие %d имеет номер %d, ожидался %d", i, event.seq, tt.wantSeqs[i])
+				}
+			}
+		})
+	}
+}
@@ -1,12 +1,17 @@
-import React, { useState, useEffect, useCallback } from 'react';
+import React, { useState, useEffect, useCallback, useRef } from 'react';
 import './App.css';
 import { 
   Message, 
   Channel, 
   User, 
-  StatusType 
+  StatusType,
+  UnreadCount,
+  AppNotification,
+  Attachment
 } from './types';
 import { api } from './services/api';
+import { EventsOn } from '../wailsjs/runtime/runtime';
+import { main } from '../wailsjs/go/models';
 import { useWebSocket } from './hooks/useWebSocket';
 import { Login } from './components/Login';
 import { UserPanel } from './components/UserPanel';
@@ -16,18 +21,23 @@ import { ChatHeader } from './components/ChatHeader';
 import { MessagesList } from './components/MessagesList';
 import { MessageComposer } from './components/MessageComposer';
 import { ChannelMembers } from './components/ChannelMembers';
+import { SearchPanel } from './components/SearchPanel';
 
 function App() {
   // Состояние авторизации
   const [isLoggedIn, setIsLoggedIn] = useState(false);
   const [currentUser, setCurrentUser] = useState('');
   const [currentUserStatus, setCurrentUserStatus] = useState<StatusType>('online');
+  const [authToken, setAuthToken] = useState('');
+  const refreshTokenRef = useRef('');
 
   // Состояние данных
   const [messages, setMessages] = useState<Message[]>([]);
   const [channels, setChannels] = useState<Channel[]>([]);
   const [users, setUsers] = useState<User[]>([]);
   const [currentChannel, setCurrentChannel] = useState('general');
+  const [unreadCounts, setUnreadCounts] = useState<Record<string, UnreadCount>>({});
+  const lastReadRef = useRef<Record<string, string>>({});
 
   // Состояние UI
   const [showUserPanel, setShowUserPanel] = useState(true);
@@ -37,19 +47,74 @@ function App() {
   const [isLoadingChannels, setIsLoadingChannels] = useState(false);
   const [isPostMode, setIsPostMode] = useState(false);
   const [newMessage, setNewMessage] = useState('');
+  const [pendingAttachments, setPendingAttachments] = useState<Attachment[]>([]);
+  const [serverUrl, setServerUrl] = useState('');
   const [showMembersPanel, setShowMembersPanel] = useState(false);
+  const [showSearchPanel, setShowSearchPanel] = useState(false);
 
   // Состояние drag & drop
   const [isDragging, setIsDragging] = useState<string | null>(null);
   const [dragOver, setDragOver] = useState<string | null>(null);
 
   // WebSocket
-  const { isConnected, subscribeToChannel, changeStatus } = useWebSocket(
+  const {
+    isConnected,
+    subscribeToChannel,
+    changeStatus,
+    sendChatMessage,
+    typingUsers,
+    startTyping,
+    stopTyping
+  } = useWebSocket(
     currentUser,
+    authToken,
     handleStatusUpdate,
-    handleNewMessage
+    handleNewMessage,
+    handleAuthError,
+    handleResync,
+    handleReadMarker,
+    handlePinUpdate
   );
 
+  // Обновление токенов: access-токен живёт недолго, refresh-токен одноразовый
+  const refreshSession = useCallback(async () => {
+    try {
+      const user = await api.auth.refresh(refreshTokenRef.current);
+      refreshTokenRef.current = user.refreshToken;
+      setAuthToken(user.token);
+    } catch (error) {
+      console.error('Сессия истекла:', error);
+      handleSessionLost();
+    }
+  }, []);
+
+  function handleAuthError(expired: boolean) {
+    if (expired) {
+      refreshSession();
+    } else {
+      handleSessionLost();
+    }
+  }
+
+  // Пропущенные события не удалось дослать - перечитываем открытый канал
+  function handleResync() {
+    loadMessages();
+  }
+
+  function handleSessionLost() {
+    refreshTokenRef.current = '';
+    setAuthToken('');
+    setCurrentUser('');
+    setIsLoggedIn(false);
+  }
+
+  useEffect(() => {
+    if (!isLoggedIn) return;
+
+    const interval = setInterval(refreshSession, 10 * 60 * 1000);
+    return () => clearInterval(interval);
+  }, [isLoggedIn, refreshSession]);
+
   // Обработчики WebSocket
   function handleStatusUpdate(username: string, status: string) {
     setUsers(prev => prev.map(user => 
@@ -71,17 +136,69 @@ function App() {
         }
         return prev;
       });
+      markRead(channel, message.id);
+    } else if (message.user !== currentUser) {
+      const mentioned = message.mentions?.includes(currentUser) ?? false;
+      setUnreadCounts(prev => {
+        const count = prev[channel] || { channel, unread: 0, mentions: 0, hasMore: false };
+        return {
+          ...prev,
+          [channel]: { ...count, unread: count.unread + 1, mentions: count.mentions + (mentioned ? 1 : 0) },
+        };
+      });
     }
   }
 
+  // Непрочитанные и отметки прочтения
+  function handleReadMarker(channel: string) {
+    setUnreadCounts(prev => ({ ...prev, [channel]: { channel, unread: 0, mentions: 0, hasMore: false } }));
+  }
+
+  const markRead = (channel: string, messageId: string) => {
+    // Список сообщений перечитывается по таймеру - не дёргаем бэкенд без нужды
+    if (lastReadRef.current[channel] === messageId) return;
+    lastReadRef.current[channel] = messageId;
+
+    handleReadMarker(channel);
+    api.messages.markRead(channel, currentUser, messageId).catch(error => {
+      console.error('Ошибка отметки прочтения:', error);
+    });
+  };
+
+  // Заглушить канал или вернуть уведомления
+  const toggleChannelMute = async (channel: string) => {
+    const muted = !unreadCounts[channel]?.muted;
+    try {
+      await api.preferences.updateChannel(currentUser, channel, main.ChannelPreferences.createFrom({ channel, muted }));
+      setUnreadCounts(prev => ({
+        ...prev,
+        [channel]: { ...(prev[channel] || { channel, unread: 0, mentions: 0, hasMore: false }), muted },
+      }));
+    } catch (error) {
+      console.error('Ошибка изменения настроек канала:', error);
+    }
+  };
+
+  const loadUnreadCounts = async () => {
+    try {
+      const counts = await api.channels.getUnreadCounts(currentUser);
+      setUnreadCounts(Object.fromEntries((counts || []).map(count => [count.channel, count])));
+    } catch (error) {
+      console.error('Ошибка загрузки непрочитанных:', error);
+    }
+  };
+
   // Загрузка данных
   const loadMessages = async () => {
     try {
-      const msgs = await api.messages.getByChannel(currentChannel);
+      const msgs = await api.messages.getByChannel(currentChannel, currentUser);
       const uniqueMessages = msgs.filter((msg, index, self) =>
         index === self.findIndex((m) => m.id === msg.id)
       );
       setMessages(uniqueMessages || []);
+      if (uniqueMessages.length > 0) {
+        markRead(currentChannel, uniqueMessages[uniqueMessages.length - 1].id);
+      }
     } catch (error) {
       console.error('Ошибка загрузки сообщений:', error);
     }
@@ -90,7 +207,7 @@ function App() {
   const loadChannels = async () => {
     setIsLoadingChannels(true);
     try {
-      const channelsList = await api.channels.getAll();
+      const channelsList = await api.channels.getAll(currentUser);
       setChannels(channelsList || []);
     } catch (error) {
       console.error('Ошибка загрузки каналов:', error);
@@ -145,6 +262,7 @@ function App() {
       loadMessages();
       loadChannels();
       loadUsers();
+      loadUnreadCounts();
     }
   }, [currentChannel, isLoggedIn]);
 
@@ -166,21 +284,74 @@ function App() {
     }
   }, [currentChannel, isConnected, subscribeToChannel]);
 
+  // Вложения отдаёт тот же HTTP-сервер, что и WebSocket
+  useEffect(() => {
+    if (!isLoggedIn) return;
+    api.realtime.getUrl().then(url => setServerUrl(url.replace(/^ws/, 'http').replace(/\/ws$/, '')));
+  }, [isLoggedIn]);
+
+  // Уведомления рабочего стола: бэкенд решает, о чём уведомлять,
+  // и не беспокоит, пока открытый канал и так на экране
+  useEffect(() => {
+    if (!isLoggedIn) return;
+
+    if ('Notification' in window && Notification.permission === 'default') {
+      Notification.requestPermission();
+    }
+
+    const reportFocus = () => api.notifications.setWindowFocus(document.hasFocus(), currentChannel);
+    reportFocus();
+    window.addEventListener('focus', reportFocus);
+    window.addEventListener('blur', reportFocus);
+
+    const off = EventsOn('notification', (notification: AppNotification) => {
+      if (!('Notification' in window) || Notification.permission !== 'granted') return;
+      const shown = new Notification(notification.title, { body: notification.body, tag: notification.id });
+      shown.onclick = () => {
+        window.focus();
+        setCurrentChannel(notification.channel);
+      };
+    });
+
+    return () => {
+      window.removeEventListener('focus', reportFocus);
+      window.removeEventListener('blur', reportFocus);
+      off();
+    };
+  }, [isLoggedIn, currentChannel]);
+
   // Обработчики действий
-  const handleLogin = (username: string) => {
+  const handleLogin = (username: string, token: string, refreshToken: string) => {
+    refreshTokenRef.current = refreshToken;
+    setAuthToken(token);
     setCurrentUser(username);
     setIsLoggedIn(true);
   };
 
+  const handleMessageChange = (message: string) => {
+    setNewMessage(message);
+    if (message.trim()) {
+      startTyping(currentChannel);
+    } else {
+      stopTyping(currentChannel);
+    }
+  };
+
   const handleSendMessage = async () => {
-    if (newMessage.trim()) {
+    stopTyping(currentChannel);
+    if (newMessage.trim() || pendingAttachments.length > 0) {
       try {
-        if (isPostMode) {
+        if (isConnected) {
+          await sendChatMessage(currentChannel, newMessage, isPostMode, pendingAttachments);
+        } else if (pendingAttachments.length > 0) {
+          await api.messages.sendWithAttachments(currentUser, newMessage, currentChannel, pendingAttachments);
+        } else if (isPostMode) {
           await api.messages.sendPost(currentUser, newMessage, currentChannel);
         } else {
           await api.messages.send(currentUser, newMessage, currentChannel);
         }
         setNewMessage('');
+        setPendingAttachments([]);
         setIsPostMode(false);
         setTimeout(() => loadMessages(), 100);
       } catch (error) {
@@ -189,6 +360,49 @@ function App() {
     }
   };
 
+  // Вложения: файл загружается сразу при выборе, к сообщению
+  // прикрепляются уже сохранённые на сервере
+  const handleAttachFile = (file: File) => {
+    const reader = new FileReader();
+    reader.onload = async () => {
+      const data = (reader.result as string).split(',')[1] || '';
+      try {
+        const attachment = await api.attachments.upload(currentUser, file.name, data);
+        setPendingAttachments(prev => [...prev, attachment]);
+      } catch (error) {
+        console.error('Ошибка загрузки файла:', error);
+        alert(`Не удалось загрузить ${file.name}: ${error}`);
+      }
+    };
+    reader.readAsDataURL(file);
+  };
+
+  // Ссылка на вложение: сервер проверяет токен и доступ к каналу сообщения
+  const attachmentUrl = (message: Message, hash: string) => {
+    const params = new URLSearchParams({ channel: message.channel, message: message.id, token: authToken });
+    return `${serverUrl}/attachments/${hash}?${params}`;
+  };
+
+  // Закреплённые сообщения: список канала перечитывается по pin_update
+  // Смена порядка не меняет сами сообщения - перечитывать нечего
+  function handlePinUpdate(channel: string, _messageId: string, _pinned: boolean, reordered: boolean) {
+    if (channel === currentChannel && !reordered) {
+      loadMessages();
+    }
+  }
+
+  const handleTogglePin = async (message: Message) => {
+    try {
+      if (message.pinnedBy) {
+        await api.pins.unpin(currentChannel, message.id, currentUser);
+      } else {
+        await api.pins.pin(currentChannel, message.id, currentUser);
+      }
+    } catch (error) {
+      console.error('Ошибка закрепления:', error);
+    }
+  };
+
   const handleAddReaction = async (messageId: string, emoji: string) => {
     try {
       await api.messages.addReaction(messageId, emoji, currentUser, currentChannel);
@@ -351,8 +565,10 @@ function App() {
         isLoading={isLoadingChannels}
         currentUser={currentUser}
         onChannelChange={setCurrentChannel}
+        unreadCounts={unreadCounts}
         onCreateChannel={() => setShowCreateChannelModal(true)}
         onDeleteChannel={handleDeleteChannel}
+        onToggleMute={toggleChannelMute}
         isDragging={isDragging}
         dragOver={dragOver}
         onDragStart={handleDragStart}
@@ -379,6 +595,7 @@ function App() {
           onStartVideoCall={startVideoCall}
           onStartAudioCall={startAudioCall}
           onShowMembers={() => setShowMembersPanel(true)} 
+          onShowSearch={() => setShowSearchPanel(true)}
         />
 
         <MessagesList
@@ -386,14 +603,26 @@ function App() {
           currentChannel={currentChannel}
           currentUser={currentUser}
           onAddReaction={handleAddReaction}
+          onTogglePin={handleTogglePin}
+          attachmentUrl={attachmentUrl}
         />
 
+        {(typingUsers[currentChannel] || []).length > 0 && (
+          <div className="typing-indicator">
+            {typingUsers[currentChannel].join(', ')}{' '}
+            {typingUsers[currentChannel].length === 1 ? 'is typing…' : 'are typing…'}
+          </div>
+        )}
+
         <MessageComposer
           currentChannel={currentChannel}
           isPostMode={isPostMode}
           message={newMessage}
           onSend={handleSendMessage}
-          onMessageChange={setNewMessage}
+          onMessageChange={handleMessageChange}
+          attachments={pendingAttachments}
+          onAttachFile={handleAttachFile}
+          onRemoveAttachment={(hash) => setPendingAttachments(prev => prev.filter(a => a.hash !== hash))}
           onTogglePostMode={() => setIsPostMode(!isPostMode)}
         />
       </div>
@@ -407,6 +636,14 @@ function App() {
           onClose={() => setShowMembersPanel(false)}
         />
       )}
+
+      {showSearchPanel && (
+        <SearchPanel
+          currentUser={currentUser}
+          onOpenMessage={(message) => setCurrentChannel(message.channel)}
+          onClose={() => setShowSearchPanel(false)}
+        />
+      )}
     </div>
   );
 }
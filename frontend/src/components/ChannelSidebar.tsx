@@ -1,5 +1,5 @@
 import React, { useState } from 'react';
-import { Channel } from '../types';
+import { Channel, UnreadCount } from '../types';
 
 interface ChannelSidebarProps {
   channels: Channel[];
@@ -9,12 +9,14 @@ interface ChannelSidebarProps {
   onChannelChange: (channel: string) => void;
   onCreateChannel: () => void;
   onDeleteChannel: (channelName: string) => void;
+  onToggleMute: (channelName: string) => void;
   isDragging: string | null;
   dragOver: string | null;
   onDragStart: (e: React.DragEvent, channelId: string) => void;
   onDragOver: (e: React.DragEvent, channelId: string) => void;
   onDragLeave: () => void;
   onDrop: (e: React.DragEvent, dropTargetId: string) => void;
+  unreadCounts: Record<string, UnreadCount>;
 }
 
 export const ChannelSidebar: React.FC<ChannelSidebarProps> = ({
@@ -25,12 +27,14 @@ export const ChannelSidebar: React.FC<ChannelSidebarProps> = ({
   onChannelChange,
   onCreateChannel,
   onDeleteChannel,
+  onToggleMute,
   isDragging,
   dragOver,
   onDragStart,
   onDragOver,
   onDragLeave,
   onDrop,
+  unreadCounts,
 }) => {
   return (
     <div className="sidebar">
@@ -56,7 +60,7 @@ export const ChannelSidebar: React.FC<ChannelSidebarProps> = ({
               key={channel.id}
               className={`channel ${currentChannel === channel.name ? 'active' : ''} ${
                 isDragging === channel.id ? 'dragging' : ''
-              } ${dragOver === channel.id ? 'drag-over' : ''}`}
+              } ${dragOver === channel.id ? 'drag-over' : ''} ${unreadCounts[channel.name]?.muted ? 'muted' : ''}`}
               onClick={() => onChannelChange(channel.name)}
               title={channel.description || channel.name}
               draggable
@@ -69,7 +73,25 @@ export const ChannelSidebar: React.FC<ChannelSidebarProps> = ({
                 <span className="drag-handle">⋮⋮</span>
                 <span className="channel-hashtag">#</span>
                 <span className="channel-name">{channel.name}</span>
+                {unreadCounts[channel.name]?.unread > 0 && (
+                  <span className={`unread-badge ${unreadCounts[channel.name].mentions > 0 ? 'mention' : ''}`}>
+                    {unreadCounts[channel.name].mentions > 0
+                      ? `@${unreadCounts[channel.name].mentions}`
+                      : unreadCounts[channel.name].hasMore ? '100+' : unreadCounts[channel.name].unread}
+                  </span>
+                )}
               </div>
+
+              <button
+                className="mute-channel-btn"
+                onClick={(e) => {
+                  e.stopPropagation();
+                  onToggleMute(channel.name);
+                }}
+                title={unreadCounts[channel.name]?.muted ? 'Unmute channel' : 'Mute channel'}
+              >
+                {unreadCounts[channel.name]?.muted ? '🔕' : '🔔'}
+              </button>
               
               {channel.createdBy === currentUser && channel.createdBy !== 'system' && (
                 <button 
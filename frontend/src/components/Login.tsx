@@ -2,7 +2,7 @@ import React, { useState } from 'react';
 import { api } from '../services/api';
 
 interface LoginProps {
-  onLogin: (username: string) => void;
+  onLogin: (username: string, token: string, refreshToken: string) => void;
 }
 
 interface User {
@@ -12,6 +12,8 @@ interface User {
   isOnline: boolean;
   status: 'online' | 'away' | 'offline';
   lastSeen?: string;
+  token: string;
+  refreshToken: string;
 }
 
 export const Login: React.FC<LoginProps> = ({ onLogin }) => {
@@ -56,7 +58,7 @@ export const Login: React.FC<LoginProps> = ({ onLogin }) => {
     try {
       const user: User = await api.auth.login(email, password) as User;
       setSuccess(`✅ Welcome back, ${user.username}!`);
-      setTimeout(() => onLogin(user.username), 1000);
+      setTimeout(() => onLogin(user.username, user.token, user.refreshToken), 1000);
     } catch (error: any) {
       const errorMessage = error.message || error.toString();
       
@@ -99,7 +101,7 @@ export const Login: React.FC<LoginProps> = ({ onLogin }) => {
     try {
       const user: User = await api.auth.register(email, password) as User;
       setSuccess(`✅ Account created successfully! Welcome, ${user.username}!`);
-      setTimeout(() => onLogin(user.username), 1500);
+      setTimeout(() => onLogin(user.username, user.token, user.refreshToken), 1500);
     } catch (error: any) {
       const errorMessage = error.message || error.toString();
       
@@ -1,5 +1,5 @@
 import React, { useRef, useEffect, useState } from 'react';
-import { EMOJI_LIST } from '../types';
+import { Attachment, EMOJI_LIST } from '../types';
 
 interface MessageComposerProps {
   currentChannel: string;
@@ -8,6 +8,9 @@ interface MessageComposerProps {
   onSend: () => void;
   onMessageChange: (message: string) => void;
   onTogglePostMode: () => void;
+  attachments: Attachment[];
+  onAttachFile: (file: File) => void;
+  onRemoveAttachment: (hash: string) => void;
 }
 
 export const MessageComposer: React.FC<MessageComposerProps> = ({
@@ -17,8 +20,12 @@ export const MessageComposer: React.FC<MessageComposerProps> = ({
   onSend,
   onMessageChange,
   onTogglePostMode,
+  attachments,
+  onAttachFile,
+  onRemoveAttachment,
 }) => {
   const [showEmojiPopup, setShowEmojiPopup] = useState(false);
+  const fileInputRef = useRef<HTMLInputElement>(null);
   const textareaRef = useRef<HTMLTextAreaElement>(null);
   const emojiPopupRef = useRef<HTMLDivElement>(null);
 
@@ -76,6 +83,17 @@ export const MessageComposer: React.FC<MessageComposerProps> = ({
         </div>
       )}
       
+      {attachments.length > 0 && (
+        <div className="composer-attachments">
+          {attachments.map(attachment => (
+            <span key={attachment.hash} className="composer-attachment">
+              📎 {attachment.name}
+              <button onClick={() => onRemoveAttachment(attachment.hash)} title="Remove">✕</button>
+            </span>
+          ))}
+        </div>
+      )}
+
       <div className="composer-input-wrapper">
         <textarea
           ref={textareaRef}
@@ -97,6 +115,25 @@ export const MessageComposer: React.FC<MessageComposerProps> = ({
           >
             📝
           </button>
+
+          <button
+            className="toolbar-icon-btn"
+            onClick={() => fileInputRef.current?.click()}
+            title="Attach file"
+            disabled={isPostMode}
+          >
+            📎
+          </button>
+          <input
+            ref={fileInputRef}
+            type="file"
+            multiple
+            style={{ display: 'none' }}
+            onChange={(e) => {
+              Array.from(e.target.files || []).forEach(onAttachFile);
+              e.target.value = '';
+            }}
+          />
           
           <div className="emoji-trigger" ref={emojiPopupRef}>
             <button 
@@ -130,7 +167,7 @@ export const MessageComposer: React.FC<MessageComposerProps> = ({
         <button 
           className="send-button"
           onClick={onSend}
-          disabled={!message.trim()}
+          disabled={!message.trim() && attachments.length === 0}
           title="Send message (Enter)"
         >
           <svg width="16" height="16" viewBox="0 0 20 20" fill="currentColor">
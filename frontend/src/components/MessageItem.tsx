@@ -5,12 +5,16 @@ interface MessageItemProps {
   message: Message;
   currentUser: string;
   onAddReaction: (messageId: string, emoji: string) => void;
+  onTogglePin: (message: Message) => void;
+  attachmentUrl: (message: Message, hash: string) => string;
 }
 
 export const MessageItem: React.FC<MessageItemProps> = ({
   message,
   currentUser,
   onAddReaction,
+  onTogglePin,
+  attachmentUrl,
 }) => {
   const [isHovered, setIsHovered] = useState(false);
 
@@ -26,6 +30,12 @@ export const MessageItem: React.FC<MessageItemProps> = ({
     return message.reactions?.[emoji]?.includes(currentUser) || false;
   };
 
+  const formatSize = (size: number) => {
+    if (size < 1024) return `${size} B`;
+    if (size < 1024 * 1024) return `${(size / 1024).toFixed(1)} KB`;
+    return `${(size / 1024 / 1024).toFixed(1)} MB`;
+  };
+
   const formatTime = (timestamp: string) => {
     return new Date(timestamp).toLocaleTimeString([], { 
       hour: '2-digit', 
@@ -43,8 +53,42 @@ export const MessageItem: React.FC<MessageItemProps> = ({
         <span className="message-user">{message.user}</span>
         <span className="message-time">{formatTime(message.timestamp)}</span>
         {message.isPost && <span className="post-badge">📌 Post</span>}
+        {message.pinnedBy && (
+          <span className="pinned-badge" title={`Pinned by ${message.pinnedBy}`}>📍 Pinned</span>
+        )}
       </div>
-      <div className="message-text">{message.text}</div>
+      {message.text && <div className="message-text">{message.text}</div>}
+
+      {message.attachments && message.attachments.length > 0 && (
+        <div className="message-attachments">
+          {message.attachments.map(attachment => (
+            attachment.width ? (
+              <a
+                key={attachment.hash}
+                href={attachmentUrl(message, attachment.hash)}
+                target="_blank"
+                rel="noreferrer"
+                title={attachment.name}
+              >
+                <img
+                  className="attachment-image"
+                  src={attachmentUrl(message, attachment.thumbnailHash || attachment.hash)}
+                  alt={attachment.name}
+                />
+              </a>
+            ) : (
+              <a
+                key={attachment.hash}
+                className="attachment-file"
+                href={attachmentUrl(message, attachment.hash)}
+                download={attachment.name}
+              >
+                📄 {attachment.name} <span className="attachment-size">{formatSize(attachment.size)}</span>
+              </a>
+            )
+          ))}
+        </div>
+      )}
       
       <div className="message-footer">
         {message.reactions && Object.entries(message.reactions).some(([_, users]) => users.length > 0) && (
@@ -76,6 +120,13 @@ export const MessageItem: React.FC<MessageItemProps> = ({
                 {emoji}
               </button>
             ))}
+            <button
+              className="quick-reaction-btn"
+              onClick={() => onTogglePin(message)}
+              title={message.pinnedBy ? 'Unpin message' : 'Pin message'}
+            >
+              {message.pinnedBy ? '✖️' : '📍'}
+            </button>
           </div>
         )}
       </div>
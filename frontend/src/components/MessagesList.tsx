@@ -7,6 +7,8 @@ interface MessagesListProps {
   currentChannel: string;
   currentUser: string;
   onAddReaction: (messageId: string, emoji: string) => void;
+  onTogglePin: (message: Message) => void;
+  attachmentUrl: (message: Message, hash: string) => string;
 }
 
 export const MessagesList: React.FC<MessagesListProps> = ({
@@ -14,6 +16,8 @@ export const MessagesList: React.FC<MessagesListProps> = ({
   currentChannel,
   currentUser,
   onAddReaction,
+  onTogglePin,
+  attachmentUrl,
 }) => {
   const messagesEndRef = useRef<HTMLDivElement>(null);
 
@@ -46,6 +50,8 @@ export const MessagesList: React.FC<MessagesListProps> = ({
           message={msg}
           currentUser={currentUser}
           onAddReaction={onAddReaction}
+          onTogglePin={onTogglePin}
+          attachmentUrl={attachmentUrl}
         />
       ))}
       <div ref={messagesEndRef} />
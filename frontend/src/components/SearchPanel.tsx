@@ -0,0 +1,106 @@
+import React, { useState } from 'react';
+import { api } from '../services/api';
+import { Message, SearchResult, TextRange } from '../types';
+
+interface SearchPanelProps {
+  currentUser: string;
+  onOpenMessage: (message: Message) => void;
+  onClose: () => void;
+}
+
+const PAGE_SIZE = 20;
+const THREAD_SEPARATOR = ':thread:';
+
+// Канал, в котором показывать результат: для ответа в треде - канал корня
+const rootChannel = (channel: string) => channel.split(THREAD_SEPARATOR)[0];
+
+// Подсвечиваем совпадения; позиции приходят в символах, а не в UTF-16
+const highlight = (text: string, ranges: TextRange[]) => {
+  const chars = Array.from(text);
+  const parts: React.ReactNode[] = [];
+  let pos = 0;
+  ranges.forEach((range, i) => {
+    if (range.start > pos) parts.push(chars.slice(pos, range.start).join(''));
+    parts.push(<mark key={i}>{chars.slice(range.start, range.end).join('')}</mark>);
+    pos = range.end;
+  });
+  if (pos < chars.length) parts.push(chars.slice(pos).join(''));
+  return parts;
+};
+
+export const SearchPanel: React.FC<SearchPanelProps> = ({
+  currentUser,
+  onOpenMessage,
+  onClose
+}) => {
+  const [text, setText] = useState('');
+  const [results, setResults] = useState<SearchResult[]>([]);
+  const [total, setTotal] = useState(0);
+  const [nextOffset, setNextOffset] = useState<number | null>(null);
+  const [error, setError] = useState('');
+  const [searched, setSearched] = useState(false);
+
+  const runSearch = async (offset: number) => {
+    try {
+      const page = await api.messages.search(currentUser, { text, offset, limit: PAGE_SIZE });
+      setResults(prev => offset === 0 ? page.results : [...prev, ...page.results]);
+      setTotal(page.total);
+      setNextOffset(page.hasMore ? page.nextOffset : null);
+      setError('');
+    } catch (err) {
+      setError(String(err));
+      setResults([]);
+      setTotal(0);
+      setNextOffset(null);
+    }
+    setSearched(true);
+  };
+
+  const handleSubmit = (e: React.FormEvent) => {
+    e.preventDefault();
+    if (text.trim()) runSearch(0);
+  };
+
+  return (
+    <div className="channel-members-panel search-panel">
+      <div className="channel-members-header">
+        <form className="search-form" onSubmit={handleSubmit}>
+          <input
+            autoFocus
+            value={text}
+            onChange={(e) => setText(e.target.value)}
+            placeholder="Поиск: слова, in:#канал, from:@автор, has:attachment"
+          />
+        </form>
+        {searched && !error && <span className="search-total">Найдено: {total}</span>}
+        <button className="close-btn" onClick={onClose}>×</button>
+      </div>
+
+      <div className="search-results">
+        {error && <div className="search-error">{error}</div>}
+        {results.map(result => (
+          <div
+            key={result.message.id}
+            className="search-result"
+            onClick={() => onOpenMessage({ ...result.message, channel: rootChannel(result.message.channel) })}
+          >
+            <div className="search-result-meta">
+              <span className="search-result-user">{result.message.user}</span>
+              <span className="search-result-channel"># {rootChannel(result.message.channel)}</span>
+              <span className="search-result-time">{new Date(result.message.timestamp).toLocaleString()}</span>
+            </div>
+            <div className="search-result-text">{highlight(result.message.text, result.highlights)}</div>
+            {result.message.attachments && result.message.attachments.length > 0 && (
+              <div className="search-result-attachments">
+                📎 {result.message.attachments.map(a => a.name).join(', ')}
+              </div>
+            )}
+          </div>
+        ))}
+        {nextOffset !== null && (
+          <button className="search-more-btn" onClick={() => runSearch(nextOffset)}>Ещё</button>
+        )}
+      </div>
+    </div>
+  );
+};
@@ -1,18 +1,52 @@
-import { useState, useEffect, useCallback } from 'react';
-import { Message } from '../types';
+import { useState, useEffect, useCallback, useRef } from 'react';
+import { Attachment, Message } from '../types';
+import { api } from '../services/api';
 
 interface WSMessage {
   type: string;
   payload: any;
+  stream?: string;
+  seq?: number;
 }
 
+// Что уже получено: эпоха сервера и последний номер события по каждому потоку.
+// Передаётся при переподключении, чтобы сервер дослал пропущенное.
+interface ResumeState {
+  epoch: string;
+  streams: Record<string, number>;
+}
+
+// Отправка, ждущая ack. После переподключения уходит повторно с тем же
+// clientId - сервер не создаст дубликат.
+interface PendingSend {
+  payload: { clientId: string; channel: string; text: string; isPost: boolean; attachments: Attachment[] };
+  resolve: (messageId: string) => void;
+  reject: (error: Error) => void;
+}
+
+// Коды закрытия, которыми сервер отклоняет токен
+const CLOSE_AUTH_REQUIRED = 4001;
+const CLOSE_TOKEN_EXPIRED = 4002;
+const CLOSE_SESSION_REVOKED = 4003;
+
 export const useWebSocket = (
   username: string,
+  token: string,
   onStatusUpdate: (username: string, status: string) => void,
-  onNewMessage: (channel: string, message: Message) => void
+  onNewMessage: (channel: string, message: Message) => void,
+  onAuthError: (expired: boolean) => void,
+  onResync: (streams: string[]) => void,
+  onReadMarker: (channel: string, messageId: string) => void,
+  onPinUpdate: (channel: string, messageId: string, pinned: boolean, reordered: boolean) => void
 ) => {
   const [ws, setWs] = useState<WebSocket | null>(null);
   const [isConnected, setIsConnected] = useState(false);
+  const tokenRef = useRef(token);
+  const resumeRef = useRef<ResumeState>({ epoch: '', streams: {} });
+  const pendingRef = useRef<Map<string, PendingSend>>(new Map());
+  const socketRef = useRef<WebSocket | null>(null);
+  const [typingUsers, setTypingUsers] = useState<Record<string, string[]>>({});
+  const typingSentRef = useRef<Record<string, number>>({});
 
   // ✅ Функция для отправки сообщений
   const sendMessage = useCallback((type: string, payload: any) => {
@@ -22,11 +56,15 @@ export const useWebSocket = (
     }
   }, [ws, isConnected]);
 
-  const connect = useCallback(() => {
-    if (!username) return;
+  const connect = useCallback(async () => {
+    if (!username || !tokenRef.current) return;
 
-    const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
-    const wsUrl = `${protocol}//${window.location.host}/ws?username=${username}`;
+    // Webview Wails не обслуживает /ws, адрес встроенного сервера отдаёт бэкенд
+    const baseUrl = await api.realtime.getUrl();
+    let wsUrl = `${baseUrl}?token=${encodeURIComponent(tokenRef.current)}`;
+    if (resumeRef.current.epoch) {
+      wsUrl += `&resume=${encodeURIComponent(JSON.stringify(resumeRef.current))}`;
+    }
     
     const socket = new WebSocket(wsUrl);
     
@@ -35,9 +73,19 @@ export const useWebSocket = (
       setIsConnected(true);
     };
     
-    socket.onclose = () => {
+    socket.onclose = (event) => {
       console.log('✗ WebSocket отключен');
       setIsConnected(false);
+
+      // Истёкший токен App обновит, и мы переподключимся уже с новым
+      if (event.code === CLOSE_TOKEN_EXPIRED) {
+        onAuthError(true);
+        return;
+      }
+      if (event.code === CLOSE_AUTH_REQUIRED || event.code === CLOSE_SESSION_REVOKED) {
+        onAuthError(false);
+        return;
+      }
       setTimeout(() => connect(), 3000);
     };
     
@@ -54,10 +102,21 @@ export const useWebSocket = (
       }
     };
     
+    socketRef.current = socket;
     setWs(socket);
   }, [username]);
 
+  const resendPending = () => {
+    pendingRef.current.forEach(({ payload }) => {
+      socketRef.current?.send(JSON.stringify({ type: 'send_message', payload }));
+    });
+  };
+
   const handleMessage = (data: WSMessage) => {
+    if (data.stream && data.seq) {
+      resumeRef.current.streams[data.stream] = data.seq;
+    }
+
     switch (data.type) {
       case 'status_update':
         const { username, status } = data.payload;
@@ -77,6 +136,60 @@ export const useWebSocket = (
         
       case 'connected':
         console.log('WebSocket: ' + data.payload.message);
+        // Сервер перезапустился - старые номера событий больше ничего не значат
+        if (data.payload.epoch !== resumeRef.current.epoch) {
+          resumeRef.current = { epoch: data.payload.epoch, streams: {} };
+        }
+        resendPending();
+        break;
+
+      case 'ack': {
+        const pending = pendingRef.current.get(data.payload.clientId);
+        pendingRef.current.delete(data.payload.clientId);
+        pending?.resolve(data.payload.messageId);
+        break;
+      }
+
+      case 'nack': {
+        const pending = pendingRef.current.get(data.payload.clientId);
+        pendingRef.current.delete(data.payload.clientId);
+        pending?.reject(new Error(data.payload.error));
+        break;
+      }
+
+      // Канал прочитан на другом устройстве
+      case 'read_marker':
+        onReadMarker(data.payload.channel, data.payload.messageId);
+        break;
+
+      case 'pin_update':
+        onPinUpdate(data.payload.channel, data.payload.messageId, data.payload.pinned, !!data.payload.reordered);
+        break;
+
+      case 'mention':
+        console.log(`🔔 ${data.payload.message.user} упомянул вас в #${data.payload.channel}`);
+        break;
+
+      case 'typing': {
+        const { channel, username, typing } = data.payload;
+        setTypingUsers(prev => {
+          const current = (prev[channel] || []).filter(u => u !== username);
+          return { ...prev, [channel]: typing ? [...current, username] : current };
+        });
+        break;
+      }
+
+      // Все пропущенные события приходят одним сообщением, по потокам
+      case 'replay':
+        data.payload.streams.forEach((stream: { stream: string; events: WSMessage[] }) => {
+          console.log(`🔁 Досланы события потока ${stream.stream}: ${stream.events.length}`);
+          stream.events.forEach(event => handleMessage(event));
+        });
+        break;
+
+      case 'resync_required':
+        console.log(`⚠️ Нужна полная перезагрузка: ${data.payload.all ? 'все потоки' : data.payload.streams.join(', ')}`);
+        onResync(data.payload.streams);
         break;
 
       // ✅ НОВОЕ: получаем список всех пользователей
@@ -93,6 +206,30 @@ export const useWebSocket = (
     }
   };
 
+  // Отправка сообщения через сокет: промис выполняется по ack с ID сообщения
+  const sendChatMessage = useCallback((channel: string, text: string, isPost = false, attachments: Attachment[] = []) => {
+    return new Promise<string>((resolve, reject) => {
+      const payload = { clientId: crypto.randomUUID(), channel, text, isPost, attachments };
+      pendingRef.current.set(payload.clientId, { payload, resolve, reject });
+      sendMessage('send_message', payload);
+    });
+  }, [sendMessage]);
+
+  // Индикатор набора: typing_start не чаще раза в 2 секунды,
+  // сервер сам погасит его, если typing_stop не придёт
+  const startTyping = useCallback((channel: string) => {
+    const now = Date.now();
+    if (now - (typingSentRef.current[channel] || 0) < 2000) return;
+    typingSentRef.current[channel] = now;
+    sendMessage('typing_start', { channel });
+  }, [sendMessage]);
+
+  const stopTyping = useCallback((channel: string) => {
+    if (!typingSentRef.current[channel]) return;
+    delete typingSentRef.current[channel];
+    sendMessage('typing_stop', { channel });
+  }, [sendMessage]);
+
   const subscribeToChannel = useCallback((channel: string) => {
     sendMessage('subscribe_channel', channel);
   }, [sendMessage]);
@@ -114,6 +251,19 @@ export const useWebSocket = (
     return () => clearInterval(pingInterval);
   }, [isConnected, sendMessage]);
 
+  // Новый токен передаём открытому соединению, а закрытое из-за
+  // истечения токена переподключаем
+  useEffect(() => {
+    tokenRef.current = token;
+    if (!token || !ws) return;
+
+    if (isConnected) {
+      sendMessage('reauth', { token });
+    } else if (ws.readyState === WebSocket.CLOSED) {
+      connect();
+    }
+  }, [token]);
+
   useEffect(() => {
     if (username) {
       connect();
@@ -131,6 +281,10 @@ export const useWebSocket = (
     isConnected, 
     subscribeToChannel,
     changeStatus, // ✅ Экспортируем
-    sendMessage 
+    sendMessage,
+    sendChatMessage,
+    typingUsers,
+    startTyping,
+    stopTyping
   };
 };
\ No newline at end of file
@@ -4,19 +4,36 @@ import {
   GetMessages, 
   Login, 
   Register,
+  RefreshSession,
   AddReaction,
   CreateChannel,
   GetChannels,
   DeleteChannel,
   JoinChannel,
   GetUsers,
-  UpdateUserStatus
+  UpdateUserStatus,
+  GetWebSocketURL,
+  MarkRead,
+  GetUnreadCounts,
+  GetNotificationSettings,
+  UpdateNotificationSettings,
+  SetWindowFocus,
+  GetPreferences,
+  UpdateChannelPreferences,
+  PinMessage,
+  UnpinMessage,
+  ReorderPins,
+  GetPinned,
+  UploadAttachment,
+  SendMessageWithAttachments,
+  SearchMessages
 } from '../../wailsjs/go/main/App';
 
 export const api = {
   auth: {
     login: Login,
-    register: Register
+    register: Register,
+    refresh: RefreshSession
   },
   users: {
     getAll: GetUsers,
@@ -27,11 +44,36 @@ export const api = {
     create: CreateChannel,
     delete: DeleteChannel,
     join: JoinChannel,
+    getUnreadCounts: GetUnreadCounts,
   },
   messages: {
     getByChannel: GetMessages,
     send: SendMessage,
     sendPost: SendPost,
+    sendWithAttachments: SendMessageWithAttachments,
     addReaction: AddReaction,
+    markRead: MarkRead,
+    search: SearchMessages,
+  },
+  attachments: {
+    upload: UploadAttachment,
+  },
+  pins: {
+    getAll: GetPinned,
+    pin: PinMessage,
+    unpin: UnpinMessage,
+    reorder: ReorderPins,
+  },
+  realtime: {
+    getUrl: GetWebSocketURL,
+  },
+  notifications: {
+    getSettings: GetNotificationSettings,
+    updateSettings: UpdateNotificationSettings,
+    setWindowFocus: SetWindowFocus,
+  },
+  preferences: {
+    get: GetPreferences,
+    updateChannel: UpdateChannelPreferences,
   },
 };
\ No newline at end of file
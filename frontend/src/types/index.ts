@@ -6,6 +6,29 @@ export interface Message {
   timestamp: string;
   reactions?: { [emoji: string]: string[] };
   isPost?: boolean;
+  mentions?: string[];
+  mentionChannel?: boolean;
+  mentionHere?: boolean;
+  pinnedBy?: string;
+  pinnedAt?: string;
+  attachments?: Attachment[];
+}
+
+export interface Attachment {
+  hash: string;
+  name: string;
+  size: number;
+  type: string;
+  width?: number;
+  height?: number;
+  thumbnailHash?: string;
+}
+
+export interface PinnedMessage {
+  messageId: string;
+  pinnedBy: string;
+  pinnedAt: string;
+  message: Message;
 }
 
 export interface Channel {
@@ -19,6 +42,53 @@ export interface Channel {
   order?: number;
 }
 
+export interface AppNotification {
+  id: string;
+  kind: 'message' | 'mention' | 'direct';
+  channel: string;
+  title: string;
+  body: string;
+  message: Message;
+}
+
+export interface SearchQuery {
+  text: string;
+  channel?: string;
+  author?: string;
+  after?: string;
+  before?: string;
+  hasAttachment?: boolean;
+  isPost?: boolean;
+  offset: number;
+  limit: number;
+}
+
+export interface TextRange {
+  start: number;
+  end: number;
+}
+
+export interface SearchResult {
+  message: Message;
+  score: number;
+  highlights: TextRange[];
+}
+
+export interface SearchPage {
+  results: SearchResult[];
+  total: number;
+  hasMore: boolean;
+  nextOffset: number;
+}
+
+export interface UnreadCount {
+  channel: string;
+  unread: number;
+  mentions: number;
+  hasMore: boolean;
+  muted?: boolean;
+}
+
 export interface User {
   id: string;
   username: string;
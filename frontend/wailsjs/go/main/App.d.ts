@@ -8,24 +8,88 @@ export function CheckAuth(arg1:string):Promise<string>;
 
 export function CreateChannel(arg1:string,arg2:string,arg3:string):Promise<main.Channel>;
 
+export function CreatePrivateChannel(arg1:string,arg2:string,arg3:string,arg4:Array<string>):Promise<main.Channel>;
+
+export function DeclineInvitation(arg1:string,arg2:string):Promise<void>;
+
 export function DeleteChannel(arg1:string,arg2:string):Promise<void>;
 
-export function GetChannels():Promise<Array<main.Channel>>;
+export function DeleteMessage(arg1:string,arg2:string,arg3:string):Promise<void>;
+
+export function EditMessage(arg1:string,arg2:string,arg3:string,arg4:string):Promise<void>;
+
+export function FollowThread(arg1:string,arg2:string,arg3:string):Promise<void>;
+
+export function GetChannels(arg1:string):Promise<Array<main.Channel>>;
+
+export function GetDirectMessages(arg1:string):Promise<Array<main.Channel>>;
+
+export function GetInvitations(arg1:string):Promise<Array<main.Invitation>>;
+
+export function GetMessageHistory(arg1:string,arg2:string,arg3:string,arg4:string,arg5:number):Promise<main.MessagePage>;
 
-export function GetMessages(arg1:string):Promise<Array<main.Message>>;
+export function GetMessageRevisions(arg1:string,arg2:string,arg3:string):Promise<Array<main.MessageRevision>>;
+
+export function GetMessages(arg1:string,arg2:string):Promise<Array<main.Message>>;
+
+export function GetNotificationSettings(arg1:string):Promise<main.NotificationSettings>;
+
+export function GetPinned(arg1:string,arg2:string):Promise<Array<main.PinnedMessage>>;
+
+export function GetPreferences(arg1:string):Promise<main.UserPreferences>;
+
+export function GetSessions(arg1:string):Promise<Array<main.SessionInfo>>;
+
+export function GetThread(arg1:string,arg2:string,arg3:string):Promise<main.Thread>;
+
+export function GetUnreadCounts(arg1:string):Promise<Array<main.UnreadCount>>;
 
 export function GetUsers():Promise<Array<main.User>>;
 
+export function GetWebSocketURL():Promise<string>;
+
+export function InviteToChannel(arg1:string,arg2:string,arg3:string):Promise<void>;
+
 export function JoinChannel(arg1:string,arg2:string):Promise<void>;
 
 export function Login(arg1:string,arg2:string):Promise<main.User>;
 
 export function Logout(arg1:string):Promise<boolean>;
 
+export function MarkRead(arg1:string,arg2:string,arg3:string):Promise<main.ReadMarker>;
+
+export function OpenDirectMessage(arg1:string,arg2:Array<string>):Promise<main.Channel>;
+
+export function PinMessage(arg1:string,arg2:string,arg3:string):Promise<void>;
+
+export function RefreshSession(arg1:string):Promise<main.User>;
+
 export function Register(arg1:string,arg2:string):Promise<main.User>;
 
+export function ReorderPins(arg1:string,arg2:string,arg3:Array<string>):Promise<void>;
+
+export function RevokeSession(arg1:string,arg2:string):Promise<void>;
+
+export function SearchMessages(arg1:string,arg2:main.SearchQuery):Promise<main.SearchPage>;
+
 export function SendMessage(arg1:string,arg2:string,arg3:string):Promise<string>;
 
+export function SendMessageWithAttachments(arg1:string,arg2:string,arg3:string,arg4:Array<main.Attachment>):Promise<string>;
+
 export function SendPost(arg1:string,arg2:string,arg3:string):Promise<string>;
 
+export function SendReply(arg1:string,arg2:string,arg3:string,arg4:string):Promise<string>;
+
+export function SetWindowFocus(arg1:boolean,arg2:string):Promise<void>;
+
+export function UnfollowThread(arg1:string,arg2:string):Promise<void>;
+
+export function UnpinMessage(arg1:string,arg2:string,arg3:string):Promise<void>;
+
+export function UpdateChannelPreferences(arg1:string,arg2:string,arg3:main.ChannelPreferences):Promise<main.ChannelPreferences>;
+
+export function UpdateNotificationSettings(arg1:string,arg2:main.NotificationSettings):Promise<main.NotificationSettings>;
+
 export function UpdateUserStatus(arg1:string,arg2:string):Promise<boolean>;
+
+export function UploadAttachment(arg1:string,arg2:string,arg3:string):Promise<main.Attachment>;
@@ -14,22 +14,90 @@ export function CreateChannel(arg1, arg2, arg3) {
   return window['go']['main']['App']['CreateChannel'](arg1, arg2, arg3);
 }
 
+export function CreatePrivateChannel(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['CreatePrivateChannel'](arg1, arg2, arg3, arg4);
+}
+
+export function DeclineInvitation(arg1, arg2) {
+  return window['go']['main']['App']['DeclineInvitation'](arg1, arg2);
+}
+
 export function DeleteChannel(arg1, arg2) {
   return window['go']['main']['App']['DeleteChannel'](arg1, arg2);
 }
 
-export function GetChannels() {
-  return window['go']['main']['App']['GetChannels']();
+export function DeleteMessage(arg1, arg2, arg3) {
+  return window['go']['main']['App']['DeleteMessage'](arg1, arg2, arg3);
+}
+
+export function EditMessage(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['EditMessage'](arg1, arg2, arg3, arg4);
+}
+
+export function FollowThread(arg1, arg2, arg3) {
+  return window['go']['main']['App']['FollowThread'](arg1, arg2, arg3);
+}
+
+export function GetChannels(arg1) {
+  return window['go']['main']['App']['GetChannels'](arg1);
+}
+
+export function GetDirectMessages(arg1) {
+  return window['go']['main']['App']['GetDirectMessages'](arg1);
+}
+
+export function GetInvitations(arg1) {
+  return window['go']['main']['App']['GetInvitations'](arg1);
+}
+
+export function GetMessageHistory(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['GetMessageHistory'](arg1, arg2, arg3, arg4, arg5);
 }
 
-export function GetMessages(arg1) {
-  return window['go']['main']['App']['GetMessages'](arg1);
+export function GetMessageRevisions(arg1, arg2, arg3) {
+  return window['go']['main']['App']['GetMessageRevisions'](arg1, arg2, arg3);
+}
+
+export function GetMessages(arg1, arg2) {
+  return window['go']['main']['App']['GetMessages'](arg1, arg2);
+}
+
+export function GetNotificationSettings(arg1) {
+  return window['go']['main']['App']['GetNotificationSettings'](arg1);
+}
+
+export function GetPinned(arg1, arg2) {
+  return window['go']['main']['App']['GetPinned'](arg1, arg2);
+}
+
+export function GetPreferences(arg1) {
+  return window['go']['main']['App']['GetPreferences'](arg1);
+}
+
+export function GetSessions(arg1) {
+  return window['go']['main']['App']['GetSessions'](arg1);
+}
+
+export function GetThread(arg1, arg2, arg3) {
+  return window['go']['main']['App']['GetThread'](arg1, arg2, arg3);
+}
+
+export function GetUnreadCounts(arg1) {
+  return window['go']['main']['App']['GetUnreadCounts'](arg1);
 }
 
 export function GetUsers() {
   return window['go']['main']['App']['GetUsers']();
 }
 
+export function GetWebSocketURL() {
+  return window['go']['main']['App']['GetWebSocketURL']();
+}
+
+export function InviteToChannel(arg1, arg2, arg3) {
+  return window['go']['main']['App']['InviteToChannel'](arg1, arg2, arg3);
+}
+
 export function JoinChannel(arg1, arg2) {
   return window['go']['main']['App']['JoinChannel'](arg1, arg2);
 }
@@ -42,18 +110,78 @@ export function Logout(arg1) {
   return window['go']['main']['App']['Logout'](arg1);
 }
 
+export function MarkRead(arg1, arg2, arg3) {
+  return window['go']['main']['App']['MarkRead'](arg1, arg2, arg3);
+}
+
+export function OpenDirectMessage(arg1, arg2) {
+  return window['go']['main']['App']['OpenDirectMessage'](arg1, arg2);
+}
+
+export function PinMessage(arg1, arg2, arg3) {
+  return window['go']['main']['App']['PinMessage'](arg1, arg2, arg3);
+}
+
+export function RefreshSession(arg1) {
+  return window['go']['main']['App']['RefreshSession'](arg1);
+}
+
 export function Register(arg1, arg2) {
   return window['go']['main']['App']['Register'](arg1, arg2);
 }
 
+export function ReorderPins(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ReorderPins'](arg1, arg2, arg3);
+}
+
+export function RevokeSession(arg1, arg2) {
+  return window['go']['main']['App']['RevokeSession'](arg1, arg2);
+}
+
+export function SearchMessages(arg1, arg2) {
+  return window['go']['main']['App']['SearchMessages'](arg1, arg2);
+}
+
 export function SendMessage(arg1, arg2, arg3) {
   return window['go']['main']['App']['SendMessage'](arg1, arg2, arg3);
 }
 
+export function SendMessageWithAttachments(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['SendMessageWithAttachments'](arg1, arg2, arg3, arg4);
+}
+
 export function SendPost(arg1, arg2, arg3) {
   return window['go']['main']['App']['SendPost'](arg1, arg2, arg3);
 }
 
+export function SendReply(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['SendReply'](arg1, arg2, arg3, arg4);
+}
+
+export function SetWindowFocus(arg1, arg2) {
+  return window['go']['main']['App']['SetWindowFocus'](arg1, arg2);
+}
+
+export function UnfollowThread(arg1, arg2) {
+  return window['go']['main']['App']['UnfollowThread'](arg1, arg2);
+}
+
+export function UnpinMessage(arg1, arg2, arg3) {
+  return window['go']['main']['App']['UnpinMessage'](arg1, arg2, arg3);
+}
+
+export function UpdateChannelPreferences(arg1, arg2, arg3) {
+  return window['go']['main']['App']['UpdateChannelPreferences'](arg1, arg2, arg3);
+}
+
+export function UpdateNotificationSettings(arg1, arg2) {
+  return window['go']['main']['App']['UpdateNotificationSettings'](arg1, arg2);
+}
+
 export function UpdateUserStatus(arg1, arg2) {
   return window['go']['main']['App']['UpdateUserStatus'](arg1, arg2);
 }
+
+export function UploadAttachment(arg1, arg2, arg3) {
+  return window['go']['main']['App']['UploadAttachment'](arg1, arg2, arg3);
+}
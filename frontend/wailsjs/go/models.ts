@@ -1,5 +1,29 @@
 export namespace main {
 	
+	export class Attachment {
+	    hash: string;
+	    name: string;
+	    size: number;
+	    type: string;
+	    width?: number;
+	    height?: number;
+	    thumbnailHash?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Attachment(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.hash = source["hash"];
+	        this.name = source["name"];
+	        this.size = source["size"];
+	        this.type = source["type"];
+	        this.width = source["width"];
+	        this.height = source["height"];
+	        this.thumbnailHash = source["thumbnailHash"];
+	    }
+	}
 	export class Channel {
 	    id: string;
 	    name: string;
@@ -9,6 +33,7 @@ export namespace main {
 	    // Go type: time
 	    createdAt: any;
 	    isPrivate: boolean;
+	    isDirect: boolean;
 	
 	    static createFrom(source: any = {}) {
 	        return new Channel(source);
@@ -23,6 +48,114 @@ export namespace main {
 	        this.createdBy = source["createdBy"];
 	        this.createdAt = this.convertValues(source["createdAt"], null);
 	        this.isPrivate = source["isPrivate"];
+	        this.isDirect = source["isDirect"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ChannelPreferences {
+	    channel: string;
+	    level?: string;
+	    muted: boolean;
+	    // Go type: time
+	    muteUntil?: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new ChannelPreferences(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.channel = source["channel"];
+	        this.level = source["level"];
+	        this.muted = source["muted"];
+	        this.muteUntil = this.convertValues(source["muteUntil"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Invitation {
+	    channel: string;
+	    invitee: string;
+	    invitedBy: string;
+	    // Go type: time
+	    createdAt: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new Invitation(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.channel = source["channel"];
+	        this.invitee = source["invitee"];
+	        this.invitedBy = source["invitedBy"];
+	        this.createdAt = this.convertValues(source["createdAt"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class MessageRevision {
+	    text: string;
+	    // Go type: time
+	    timestamp: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new MessageRevision(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.text = source["text"];
+	        this.timestamp = this.convertValues(source["timestamp"], null);
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -52,6 +185,20 @@ export namespace main {
 	    timestamp: any;
 	    reactions: Record<string, Array<string>>;
 	    isPost: boolean;
+	    // Go type: time
+	    editedAt?: any;
+	    revisions?: MessageRevision[];
+	    mentions?: string[];
+	    mentionChannel?: boolean;
+	    mentionHere?: boolean;
+	    attachments?: Attachment[];
+	    pinnedBy?: string;
+	    // Go type: time
+	    pinnedAt?: any;
+	    parentId?: string;
+	    replyCount?: number;
+	    // Go type: time
+	    lastReplyAt?: any;
 	
 	    static createFrom(source: any = {}) {
 	        return new Message(source);
@@ -66,6 +213,17 @@ export namespace main {
 	        this.timestamp = this.convertValues(source["timestamp"], null);
 	        this.reactions = source["reactions"];
 	        this.isPost = source["isPost"];
+	        this.editedAt = this.convertValues(source["editedAt"], null);
+	        this.revisions = this.convertValues(source["revisions"], MessageRevision);
+	        this.mentions = source["mentions"];
+	        this.mentionChannel = source["mentionChannel"];
+	        this.mentionHere = source["mentionHere"];
+	        this.attachments = this.convertValues(source["attachments"], Attachment);
+	        this.pinnedBy = source["pinnedBy"];
+	        this.pinnedAt = this.convertValues(source["pinnedAt"], null);
+	        this.parentId = source["parentId"];
+	        this.replyCount = source["replyCount"];
+	        this.lastReplyAt = this.convertValues(source["lastReplyAt"], null);
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -86,6 +244,359 @@ export namespace main {
 		    return a;
 		}
 	}
+	export class MessagePage {
+	    messages: Message[];
+	    nextCursor: string;
+	    hasMore: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new MessagePage(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.messages = this.convertValues(source["messages"], Message);
+	        this.nextCursor = source["nextCursor"];
+	        this.hasMore = source["hasMore"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class NotificationSettings {
+	    level: string;
+	    doNotDisturb: boolean;
+	    // Go type: time
+	    dndUntil?: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new NotificationSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.level = source["level"];
+	        this.doNotDisturb = source["doNotDisturb"];
+	        this.dndUntil = this.convertValues(source["dndUntil"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class PinnedMessage {
+	    messageId: string;
+	    pinnedBy: string;
+	    // Go type: time
+	    pinnedAt: any;
+	    message: Message;
+	
+	    static createFrom(source: any = {}) {
+	        return new PinnedMessage(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.messageId = source["messageId"];
+	        this.pinnedBy = source["pinnedBy"];
+	        this.pinnedAt = this.convertValues(source["pinnedAt"], null);
+	        this.message = this.convertValues(source["message"], Message);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ReadMarker {
+	    channel: string;
+	    username: string;
+	    messageId: string;
+	    // Go type: time
+	    timestamp: any;
+	    // Go type: time
+	    updatedAt: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new ReadMarker(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.channel = source["channel"];
+	        this.username = source["username"];
+	        this.messageId = source["messageId"];
+	        this.timestamp = this.convertValues(source["timestamp"], null);
+	        this.updatedAt = this.convertValues(source["updatedAt"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class TextRange {
+	    start: number;
+	    end: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new TextRange(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.start = source["start"];
+	        this.end = source["end"];
+	    }
+	}
+	export class SearchResult {
+	    message: Message;
+	    score: number;
+	    highlights: TextRange[];
+	
+	    static createFrom(source: any = {}) {
+	        return new SearchResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.message = this.convertValues(source["message"], Message);
+	        this.score = source["score"];
+	        this.highlights = this.convertValues(source["highlights"], TextRange);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SearchPage {
+	    results: SearchResult[];
+	    total: number;
+	    hasMore: boolean;
+	    nextOffset: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SearchPage(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.results = this.convertValues(source["results"], SearchResult);
+	        this.total = source["total"];
+	        this.hasMore = source["hasMore"];
+	        this.nextOffset = source["nextOffset"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SearchQuery {
+	    text: string;
+	    channel?: string;
+	    author?: string;
+	    after?: string;
+	    before?: string;
+	    hasAttachment?: boolean;
+	    isPost?: boolean;
+	    offset: number;
+	    limit: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SearchQuery(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.text = source["text"];
+	        this.channel = source["channel"];
+	        this.author = source["author"];
+	        this.after = source["after"];
+	        this.before = source["before"];
+	        this.hasAttachment = source["hasAttachment"];
+	        this.isPost = source["isPost"];
+	        this.offset = source["offset"];
+	        this.limit = source["limit"];
+	    }
+	}
+	
+	export class SessionInfo {
+	    id: string;
+	    // Go type: time
+	    createdAt: any;
+	    // Go type: time
+	    expiresAt: any;
+	    current: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new SessionInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.createdAt = this.convertValues(source["createdAt"], null);
+	        this.expiresAt = this.convertValues(source["expiresAt"], null);
+	        this.current = source["current"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class Thread {
+	    parent: Message;
+	    replies: MessagePage;
+	    following: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Thread(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.parent = this.convertValues(source["parent"], Message);
+	        this.replies = this.convertValues(source["replies"], MessagePage);
+	        this.following = source["following"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class UnreadCount {
+	    channel: string;
+	    unread: number;
+	    mentions: number;
+	    hasMore: boolean;
+	    muted: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new UnreadCount(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.channel = source["channel"];
+	        this.unread = source["unread"];
+	        this.mentions = source["mentions"];
+	        this.hasMore = source["hasMore"];
+	        this.muted = source["muted"];
+	    }
+	}
 	export class User {
 	    id: string;
 	    username: string;
@@ -93,6 +604,8 @@ export namespace main {
 	    isOnline: boolean;
 	    status: string;
 	    lastSeen?: string;
+	    token?: string;
+	    refreshToken?: string;
 	
 	    static createFrom(source: any = {}) {
 	        return new User(source);
@@ -106,7 +619,43 @@ export namespace main {
 	        this.isOnline = source["isOnline"];
 	        this.status = source["status"];
 	        this.lastSeen = source["lastSeen"];
+	        this.token = source["token"];
+	        this.refreshToken = source["refreshToken"];
+	    }
+	}
+	export class UserPreferences {
+	    username: string;
+	    notifications: NotificationSettings;
+	    channels: Record<string, ChannelPreferences>;
+	
+	    static createFrom(source: any = {}) {
+	        return new UserPreferences(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.username = source["username"];
+	        this.notifications = this.convertValues(source["notifications"], NotificationSettings);
+	        this.channels = this.convertValues(source["channels"], ChannelPreferences, true);
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
 
 }
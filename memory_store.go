@@ -0,0 +1,493 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore - реализация Store в памяти процесса для тестов и офлайн-демо.
+// Данные не переживают перезапуск приложения.
+type MemoryStore struct {
+	channels  map[string]Channel
+	invites   map[string]map[string]Invitation // invitee -> channel -> приглашение
+	messages  map[string][]Message             // channel -> сообщения в порядке отправки
+	positions map[string]int                   // ID сообщения -> позиция в списке канала
+	followers map[string][]string              // ID корня треда -> подписчики
+	sessions  map[string]Session               // хеш токена -> сессия
+	markers   map[string]map[string]ReadMarker // пользователь -> канал -> отметка прочтения
+	prefs     map[string]UserPreferences       // пользователь -> настройки
+	pins      map[string][]Pin                 // канал -> закреплённые сообщения по порядку
+	users     map[string]User                  // email -> пользователь
+	passwords map[string]string                // email -> хеш пароля
+	mu        sync.RWMutex
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		channels:  make(map[string]Channel),
+		invites:   make(map[string]map[string]Invitation),
+		messages:  make(map[string][]Message),
+		positions: make(map[string]int),
+		followers: make(map[string][]string),
+		sessions:  make(map[string]Session),
+		markers:   make(map[string]map[string]ReadMarker),
+		prefs:     make(map[string]UserPreferences),
+		pins:      make(map[string][]Pin),
+		users:     make(map[string]User),
+		passwords: make(map[string]string),
+	}
+}
+
+// cloneMessage копирует сообщение вместе с картой реакций,
+// чтобы вызывающий код не мог изменить сохранённые данные
+func cloneMessage(msg Message) Message {
+	if msg.Reactions != nil {
+		reactions := make(map[string][]string, len(msg.Reactions))
+		for emoji, users := range msg.Reactions {
+			reactions[emoji] = append([]string(nil), users...)
+		}
+		msg.Reactions = reactions
+	}
+	if msg.EditedAt != nil {
+		editedAt := *msg.EditedAt
+		msg.EditedAt = &editedAt
+	}
+	if msg.PinnedAt != nil {
+		pinnedAt := *msg.PinnedAt
+		msg.PinnedAt = &pinnedAt
+	}
+	msg.Revisions = append([]MessageRevision(nil), msg.Revisions...)
+	msg.Mentions = append([]string(nil), msg.Mentions...)
+	msg.Attachments = append([]Attachment(nil), msg.Attachments...)
+	return msg
+}
+
+func cloneChannel(channel Channel) Channel {
+	channel.Members = append([]string(nil), channel.Members...)
+	return channel
+}
+
+func (s *MemoryStore) SaveChannel(channel Channel) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.channels[channel.Name] = cloneChannel(channel)
+	return nil
+}
+
+func (s *MemoryStore) GetChannel(name string) (*Channel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	channel, exists := s.channels[name]
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	channel = cloneChannel(channel)
+	return &channel, nil
+}
+
+func (s *MemoryStore) GetAllChannels() ([]Channel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	channels := make([]Channel, 0, len(s.channels))
+	for _, channel := range s.channels {
+		channels = append(channels, cloneChannel(channel))
+	}
+	return channels, nil
+}
+
+func (s *MemoryStore) DeleteChannel(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.channels, name)
+	return nil
+}
+
+func (s *MemoryStore) SaveInvitation(invitation Invitation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.invites[invitation.Invitee] == nil {
+		s.invites[invitation.Invitee] = make(map[string]Invitation)
+	}
+	s.invites[invitation.Invitee][invitation.Channel] = invitation
+	return nil
+}
+
+func (s *MemoryStore) GetInvitations(username string) ([]Invitation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	invitations := make([]Invitation, 0, len(s.invites[username]))
+	for _, invitation := range s.invites[username] {
+		invitations = append(invitations, invitation)
+	}
+	return invitations, nil
+}
+
+func (s *MemoryStore) DeleteInvitation(channel, username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.invites[username], channel)
+	return nil
+}
+
+func (s *MemoryStore) SaveMessage(msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.positions[msg.ID] = len(s.messages[msg.Channel])
+	s.messages[msg.Channel] = append(s.messages[msg.Channel], cloneMessage(msg))
+	return nil
+}
+
+// findMessage возвращает позицию сообщения в канале, вызывается под мьютексом
+func (s *MemoryStore) findMessage(channel, messageID string) (int, bool) {
+	pos, exists := s.positions[messageID]
+	if !exists || pos >= len(s.messages[channel]) || s.messages[channel][pos].ID != messageID {
+		return 0, false
+	}
+	return pos, true
+}
+
+func (s *MemoryStore) GetMessage(channel, messageID string) (*Message, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	pos, exists := s.findMessage(channel, messageID)
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	msg := cloneMessage(s.messages[channel][pos])
+	return &msg, nil
+}
+
+func (s *MemoryStore) GetMessages(channel string, limit int64) ([]Message, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stored := s.messages[channel]
+	start := int64(len(stored)) - limit
+	if start < 0 {
+		start = 0
+	}
+
+	messages := make([]Message, 0, int64(len(stored))-start)
+	for _, msg := range stored[start:] {
+		messages = append(messages, cloneMessage(msg))
+	}
+	return messages, nil
+}
+
+func (s *MemoryStore) GetMessageHistory(channel string, query HistoryQuery) (MessagePage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stored := s.messages[channel]
+	length := int64(len(stored))
+
+	var cursorPos int64
+	if cursor := query.Before + query.After; cursor != "" {
+		after := query.After != ""
+		messageID, at, isTime := parseCursor(cursor)
+		if isTime {
+			cursorPos = int64(sort.Search(len(stored), func(i int) bool {
+				if after {
+					return stored[i].Timestamp.After(at)
+				}
+				return !stored[i].Timestamp.Before(at)
+			}))
+		} else {
+			pos, exists := s.findMessage(channel, messageID)
+			if !exists {
+				return MessagePage{}, ErrNotFound
+			}
+			cursorPos = int64(pos)
+			if after {
+				cursorPos++
+			}
+		}
+	}
+
+	start, end := historyRange(query, length, cursorPos)
+	messages := []Message{}
+	for i := start; i <= end; i++ {
+		messages = append(messages, cloneMessage(stored[i]))
+	}
+
+	return newMessagePage(messages, query, start, end, length), nil
+}
+
+func (s *MemoryStore) UpdateMessage(channel string, updatedMsg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pos, exists := s.findMessage(channel, updatedMsg.ID)
+	if !exists {
+		return fmt.Errorf("сообщение с ID %s не найдено", updatedMsg.ID)
+	}
+
+	s.messages[channel][pos] = cloneMessage(updatedMsg)
+	return nil
+}
+
+func (s *MemoryStore) ModifyMessage(channel, messageID string, modify func(msg *Message) error) (*Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pos, exists := s.findMessage(channel, messageID)
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	msg := cloneMessage(s.messages[channel][pos])
+	if err := modify(&msg); err != nil {
+		return nil, err
+	}
+
+	s.messages[channel][pos] = cloneMessage(msg)
+	return &msg, nil
+}
+
+// DeleteMessage сразу убирает сообщение из списка: в памяти перестроение
+// позиций дешёвое, поэтому надгробия не нужны
+func (s *MemoryStore) DeleteMessage(channel, messageID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pos, exists := s.findMessage(channel, messageID)
+	if !exists {
+		return ErrNotFound
+	}
+
+	stored := s.messages[channel]
+	s.messages[channel] = append(stored[:pos:pos], stored[pos+1:]...)
+	delete(s.positions, messageID)
+	for i := pos; i < len(s.messages[channel]); i++ {
+		s.positions[s.messages[channel][i].ID] = i
+	}
+	return nil
+}
+
+func (s *MemoryStore) CompactMessages(channel string) (int64, error) {
+	return 0, nil
+}
+
+func (s *MemoryStore) PendingCompaction() ([]string, error) {
+	return nil, nil
+}
+
+func (s *MemoryStore) ToggleReaction(channel, messageID, emoji, username string) (map[string][]string, error) {
+	msg, err := s.ModifyMessage(channel, messageID, func(msg *Message) error {
+		msg.Reactions = toggleReaction(msg.Reactions, emoji, username)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return msg.Reactions, nil
+}
+
+func (s *MemoryStore) AddThreadFollower(parentID, username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !contains(s.followers[parentID], username) {
+		s.followers[parentID] = append(s.followers[parentID], username)
+	}
+	return nil
+}
+
+func (s *MemoryStore) RemoveThreadFollower(parentID, username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	followers := []string{}
+	for _, follower := range s.followers[parentID] {
+		if follower != username {
+			followers = append(followers, follower)
+		}
+	}
+	s.followers[parentID] = followers
+	return nil
+}
+
+func (s *MemoryStore) GetThreadFollowers(parentID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]string{}, s.followers[parentID]...), nil
+}
+
+func (s *MemoryStore) SaveReadMarker(marker ReadMarker) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.markers[marker.Username] == nil {
+		s.markers[marker.Username] = make(map[string]ReadMarker)
+	}
+	s.markers[marker.Username][marker.Channel] = marker
+	return nil
+}
+
+func (s *MemoryStore) GetReadMarkers(username string) (map[string]ReadMarker, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	markers := make(map[string]ReadMarker, len(s.markers[username]))
+	for channel, marker := range s.markers[username] {
+		markers[channel] = marker
+	}
+	return markers, nil
+}
+
+func (s *MemoryStore) SavePins(channel string, pins []Pin) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(pins) == 0 {
+		delete(s.pins, channel)
+		return nil
+	}
+	s.pins[channel] = append([]Pin(nil), pins...)
+	return nil
+}
+
+func (s *MemoryStore) GetPins(channel string) ([]Pin, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]Pin{}, s.pins[channel]...), nil
+}
+
+// clonePreferences копирует настройки вместе с картой каналов
+func clonePreferences(prefs UserPreferences) UserPreferences {
+	channels := make(map[string]ChannelPreferences, len(prefs.Channels))
+	for channel, channelPrefs := range prefs.Channels {
+		channels[channel] = channelPrefs
+	}
+	prefs.Channels = channels
+	return prefs
+}
+
+func (s *MemoryStore) SavePreferences(prefs UserPreferences) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prefs[prefs.Username] = clonePreferences(prefs)
+	return nil
+}
+
+func (s *MemoryStore) GetPreferences(username string) (*UserPreferences, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	prefs, exists := s.prefs[username]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	prefs = clonePreferences(prefs)
+	return &prefs, nil
+}
+
+func (s *MemoryStore) SaveSession(session Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions[session.TokenHash] = session
+	return nil
+}
+
+func (s *MemoryStore) GetSession(tokenHash string) (*Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, exists := s.sessions[tokenHash]
+	if !exists || time.Now().After(session.ExpiresAt) {
+		return nil, ErrNotFound
+	}
+	return &session, nil
+}
+
+func (s *MemoryStore) DeleteSession(tokenHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, tokenHash)
+	return nil
+}
+
+func (s *MemoryStore) GetUserSessions(username string) ([]Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	sessions := []Session{}
+	for hash, session := range s.sessions {
+		if now.After(session.ExpiresAt) {
+			delete(s.sessions, hash)
+			continue
+		}
+		if session.Username == username {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+func (s *MemoryStore) SaveUser(user *User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.users[user.Email] = *user
+	return nil
+}
+
+func (s *MemoryStore) GetUser(email string) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, exists := s.users[email]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+func (s *MemoryStore) GetAllUsers() ([]User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make([]User, 0, len(s.users))
+	for _, user := range s.users {
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+func (s *MemoryStore) SaveUserPassword(email, hashedPassword string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.passwords[email] = hashedPassword
+	return nil
+}
+
+func (s *MemoryStore) GetUserPassword(email string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	password, exists := s.passwords[email]
+	if !exists {
+		return "", ErrNotFound
+	}
+	return password, nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"log"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Особые упоминания: @channel - все, кто видит канал, @here - только те,
+// кто сейчас в сети
+const (
+	mentionChannel = "channel"
+	mentionHere    = "here"
+)
+
+// mentionPattern ищет @имя в начале текста или после символа, который не
+// может быть частью адреса, поэтому bob@mail.ru не считается упоминанием
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@.+-])@([\w.+-]+)`)
+
+// MentionEvent - пользователя упомянули в сообщении. Приходит адресно,
+// даже если он не подписан на канал.
+type MentionEvent struct {
+	Username string  `json:"username"` // кого упомянули
+	Channel  string  `json:"channel"`
+	Message  Message `json:"message"`
+}
+
+// parseMentions возвращает имена после @ в порядке появления без повторов
+func parseMentions(text string) []string {
+	names := []string{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		// точка в конце предложения не часть имени: "спасибо, @bob."
+		name := strings.TrimRight(match[1], ".")
+		if name != "" && !contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// resolveMentions заполняет список упомянутых пользователей сообщения.
+// Автор в список не попадает, в приватном канале - только его участники.
+func (a *App) resolveMentions(msg *Message) {
+	names := parseMentions(msg.Text)
+	if len(names) == 0 {
+		return
+	}
+
+	channel, err := a.store.GetChannel(rootChannel(msg.Channel))
+	if err != nil && err != ErrNotFound {
+		log.Printf("Ошибка получения канала %s для упоминаний: %v", msg.Channel, err)
+		return
+	}
+	canSee := func(username string) bool {
+		return channel == nil || !channel.IsPrivate || contains(channel.Members, username)
+	}
+
+	mentions := []string{}
+	add := func(username string) {
+		if username != msg.User && canSee(username) && !contains(mentions, username) {
+			mentions = append(mentions, username)
+		}
+	}
+
+	for _, name := range names {
+		switch name {
+		case mentionChannel:
+			msg.MentionChannel = true
+			for _, user := range userManager.GetAllUsers() {
+				add(user.Username)
+			}
+		case mentionHere:
+			msg.MentionHere = true
+			for _, user := range userManager.GetAllUsers() {
+				if user.Status == "online" {
+					add(user.Username)
+				}
+			}
+		default:
+			if _, exists := userManager.GetUser(name); exists {
+				add(name)
+			}
+		}
+	}
+
+	if len(mentions) > 0 {
+		msg.Mentions = mentions
+	}
+}
+
+// notifyMentions отправляет упомянутым пользователям событие mention
+// через WebSocket. Уведомление рабочего стола локальному пользователю
+// показывает notifyDesktop. Тем, кто заглушил канал или выключил в нём
+// уведомления, событие не отправляется - упоминание останется только в
+// счётчике непрочитанных.
+func (a *App) notifyMentions(msg Message) {
+	now := time.Now()
+	for _, username := range msg.Mentions {
+		prefs, err := a.preferencesFor(username)
+		if err != nil {
+			log.Printf("Ошибка получения настроек %s: %v", username, err)
+		} else if prefs.channelLevel(rootChannel(msg.Channel), now) == notifyNone {
+			continue
+		}
+
+		event := MentionEvent{Username: username, Channel: msg.Channel, Message: msg}
+		a.hub.SendToUser(username, WSMessage{Type: "mention", Payload: event})
+	}
+	if len(msg.Mentions) > 0 {
+		log.Printf("🔔 %s упомянул в #%s: %s", msg.User, msg.Channel, strings.Join(msg.Mentions, ", "))
+	}
+}
+
+// mentionsUser проверяет, упомянут ли пользователь в сообщении
+func mentionsUser(msg Message, username string) bool {
+	return contains(msg.Mentions, username)
+}
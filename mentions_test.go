@@ -0,0 +1,33 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"без упоминаний", "просто текст", []string{}},
+		{"в начале", "@bob привет", []string{"bob"}},
+		{"в середине", "привет, @bob!", []string{"bob"}},
+		{"точка в конце предложения", "спасибо, @bob.", []string{"bob"}},
+		{"точка внутри имени", "@bob.smith глянь", []string{"bob.smith"}},
+		{"адрес почты", "пиши на bob@mail.ru", []string{}},
+		{"повторы", "@bob, @al и снова @bob", []string{"bob", "al"}},
+		{"в скобках", "(@al)", []string{"al"}},
+		{"особые", "@channel и @here", []string{"channel", "here"}},
+		{"двойная собака", "@@bob", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseMentions(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseMentions(%q) = %v, ожидалось %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
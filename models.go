@@ -10,6 +10,32 @@ type Message struct {
 	Timestamp time.Time           `json:"timestamp"`
 	Reactions map[string][]string `json:"reactions"` // emoji -> [usernames]
 	IsPost    bool                `json:"isPost"`
+	EditedAt  *time.Time          `json:"editedAt,omitempty"`
+	Revisions []MessageRevision   `json:"revisions,omitempty"` // прежние версии текста, от старых к новым
+
+	// Упоминания разбираются при отправке: Mentions - итоговый список
+	// упомянутых пользователей, включая раскрытые @channel и @here
+	Mentions       []string `json:"mentions,omitempty"`
+	MentionChannel bool     `json:"mentionChannel,omitempty"`
+	MentionHere    bool     `json:"mentionHere,omitempty"`
+
+	Attachments []Attachment `json:"attachments,omitempty"`
+
+	// Закрепление: кто и когда закрепил сообщение в канале (см. pins.go)
+	PinnedBy string     `json:"pinnedBy,omitempty"`
+	PinnedAt *time.Time `json:"pinnedAt,omitempty"`
+
+	// Треды: у ответа ParentID указывает на корневое сообщение, а Channel -
+	// на канал треда (см. threadChannel). У корня ведётся счётчик ответов.
+	ParentID    string     `json:"parentId,omitempty"`
+	ReplyCount  int        `json:"replyCount,omitempty"`
+	LastReplyAt *time.Time `json:"lastReplyAt,omitempty"`
+}
+
+// MessageRevision - версия текста сообщения до редактирования
+type MessageRevision struct {
+	Text      string    `json:"text"`
+	Timestamp time.Time `json:"timestamp"` // когда эта версия была написана
 }
 
 type Channel struct {
@@ -20,6 +46,7 @@ type Channel struct {
 	CreatedBy   string    `json:"createdBy"`
 	CreatedAt   time.Time `json:"createdAt"`
 	IsPrivate   bool      `json:"isPrivate"`
+	IsDirect    bool      `json:"isDirect"` // личная переписка, всегда приватная
 }
 
 type Reaction struct {
@@ -27,3 +54,99 @@ type Reaction struct {
 	Emoji     string `json:"emoji"`
 	Username  string `json:"username"`
 }
+
+// Invitation - приглашение пользователя в приватный канал
+type Invitation struct {
+	Channel   string    `json:"channel"`
+	Invitee   string    `json:"invitee"`
+	InvitedBy string    `json:"invitedBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ReadMarker - до какого сообщения пользователь прочитал канал
+type ReadMarker struct {
+	Channel   string    `json:"channel"`
+	Username  string    `json:"username"`
+	MessageID string    `json:"messageId"`
+	Timestamp time.Time `json:"timestamp"` // время прочитанного сообщения
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// UnreadCount - непрочитанные сообщения канала; упоминания считаются отдельно
+type UnreadCount struct {
+	Channel  string `json:"channel"`
+	Unread   int    `json:"unread"`
+	Mentions int    `json:"mentions"`
+	HasMore  bool   `json:"hasMore"` // непрочитанных больше, чем посчитано
+	Muted    bool   `json:"muted"`   // канал заглушён, клиент не выделяет его
+}
+
+// Attachment - файл, прикреплённый к сообщению. Содержимое лежит
+// в BlobStore под Hash, размер и тип определяет сервер при загрузке.
+type Attachment struct {
+	Hash          string `json:"hash"` // SHA-256 содержимого
+	Name          string `json:"name"`
+	Size          int64  `json:"size"`
+	Type          string `json:"type"` // MIME-тип по содержимому
+	Width         int    `json:"width,omitempty"`
+	Height        int    `json:"height,omitempty"`
+	ThumbnailHash string `json:"thumbnailHash,omitempty"` // только у больших картинок
+}
+
+// Pin - закреплённое сообщение в списке канала
+type Pin struct {
+	MessageID string    `json:"messageId"`
+	PinnedBy  string    `json:"pinnedBy"`
+	PinnedAt  time.Time `json:"pinnedAt"`
+}
+
+// PinnedMessage - закрепление вместе с актуальной версией сообщения
+type PinnedMessage struct {
+	Pin
+	Message Message `json:"message"`
+}
+
+// NotificationSettings - общие настройки уведомлений пользователя
+type NotificationSettings struct {
+	Level        string     `json:"level"` // "all", "mentions", "none"
+	DoNotDisturb bool       `json:"doNotDisturb"`
+	DNDUntil     *time.Time `json:"dndUntil,omitempty"` // nil - пока не выключат вручную
+}
+
+// ChannelPreferences - настройки пользователя для одного канала
+type ChannelPreferences struct {
+	Channel   string     `json:"channel"`
+	Level     string     `json:"level,omitempty"` // пусто - как в общих настройках
+	Muted     bool       `json:"muted"`
+	MuteUntil *time.Time `json:"muteUntil,omitempty"` // nil - пока не включат обратно
+}
+
+// UserPreferences - все настройки пользователя, хранятся одной записью
+type UserPreferences struct {
+	Username      string                        `json:"username"`
+	Notifications NotificationSettings          `json:"notifications"`
+	Channels      map[string]ChannelPreferences `json:"channels"` // канал -> настройки
+}
+
+// Thread - корневое сообщение и страница ответов на него
+type Thread struct {
+	Parent    Message     `json:"parent"`
+	Replies   MessagePage `json:"replies"`
+	Following bool        `json:"following"`
+}
+
+// HistoryQuery - параметры постраничной выборки истории канала.
+// Before/After - курсор: ID сообщения или время в RFC3339. Если оба пусты,
+// возвращаются последние сообщения канала.
+type HistoryQuery struct {
+	Before string `json:"before"`
+	After  string `json:"after"`
+	Limit  int64  `json:"limit"`
+}
+
+// MessagePage - страница истории в хронологическом порядке
+type MessagePage struct {
+	Messages   []Message `json:"messages"`
+	NextCursor string    `json:"nextCursor"` // курсор для следующего запроса в том же направлении
+	HasMore    bool      `json:"hasMore"`
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// Уровни уведомлений: обо всех сообщениях, только об упоминаниях и личных
+// сообщениях, либо ни о чём
+const (
+	notifyAll      = "all"
+	notifyMentions = "mentions"
+	notifyNone     = "none"
+)
+
+// Виды уведомлений
+const (
+	notificationMessage = "message"
+	notificationMention = "mention"
+	notificationDirect  = "direct"
+)
+
+// notificationDedupTTL - сколько помним показанные уведомления, чтобы
+// одно сообщение не всплыло дважды
+const notificationDedupTTL = 10 * time.Minute
+
+// Notification - уведомление рабочего стола, фронтенд показывает его
+// по событию runtime "notification"
+type Notification struct {
+	ID      string  `json:"id"` // ID сообщения
+	Kind    string  `json:"kind"`
+	Channel string  `json:"channel"` // для ответов в тредах - канал корня
+	Title   string  `json:"title"`
+	Body    string  `json:"body"`
+	Message Message `json:"message"`
+}
+
+// notificationCenter решает, о чём уведомлять пользователя, вошедшего
+// в это окно. Нулевое значение готово к работе.
+type notificationCenter struct {
+	mu            sync.Mutex
+	localUser     string // кто вошёл через окно приложения
+	focused       bool
+	activeChannel string               // открытый в окне канал
+	notified      map[string]time.Time // ID сообщения -> когда показано
+}
+
+// setLocalUser запоминает пользователя окна после входа или обновления сессии
+func (nc *notificationCenter) setLocalUser(username string) {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	nc.localUser = username
+}
+
+// clearLocalUser забывает пользователя окна при выходе
+func (nc *notificationCenter) clearLocalUser(username string) {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	if nc.localUser == username {
+		nc.localUser = ""
+	}
+}
+
+// markNotified отмечает сообщение показанным. Возвращает false, если
+// уведомление о нём уже было.
+func (nc *notificationCenter) markNotified(messageID string, now time.Time) bool {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+
+	if nc.notified == nil {
+		nc.notified = make(map[string]time.Time)
+	}
+	for id, at := range nc.notified {
+		if now.Sub(at) > notificationDedupTTL {
+			delete(nc.notified, id)
+		}
+	}
+	if _, seen := nc.notified[messageID]; seen {
+		return false
+	}
+	nc.notified[messageID] = now
+	return true
+}
+
+// SetWindowFocus сообщает, в фокусе ли окно и какой канал в нём открыт.
+// О сообщениях в открытом канале сфокусированного окна не уведомляем.
+func (a *App) SetWindowFocus(focused bool, channel string) {
+	a.notifications.mu.Lock()
+	defer a.notifications.mu.Unlock()
+	a.notifications.focused = focused
+	a.notifications.activeChannel = channel
+}
+
+// notificationKind определяет, чем сообщение является для username.
+// Пустая строка - уведомлять не о чем.
+func (a *App) notificationKind(msg Message, channel *Channel, username string) string {
+	switch {
+	case channel != nil && channel.IsDirect:
+		return notificationDirect
+	case mentionsUser(msg, username):
+		return notificationMention
+	case msg.ParentID != "":
+		// ответы в тредах - только тем, кто на тред подписан
+		followers, err := a.store.GetThreadFollowers(msg.ParentID)
+		if err != nil {
+			log.Printf("Ошибка получения подписчиков треда %s: %v", msg.ParentID, err)
+			return ""
+		}
+		if !contains(followers, username) {
+			return ""
+		}
+	}
+	return notificationMessage
+}
+
+// notifyDesktop показывает уведомление о новом сообщении пользователю окна,
+// если это разрешают его настройки
+func (a *App) notifyDesktop(msg Message) {
+	now := time.Now()
+
+	a.notifications.mu.Lock()
+	username := a.notifications.localUser
+	watching := a.notifications.focused && a.notifications.activeChannel == msg.Channel
+	a.notifications.mu.Unlock()
+
+	if username == "" || msg.User == username || watching {
+		return
+	}
+
+	channelName := rootChannel(msg.Channel)
+	channel, err := a.store.GetChannel(channelName)
+	if err != nil && err != ErrNotFound {
+		log.Printf("Ошибка получения канала %s для уведомления: %v", channelName, err)
+		return
+	}
+	if channel != nil && channel.IsPrivate && !contains(channel.Members, username) {
+		return
+	}
+
+	kind := a.notificationKind(msg, channel, username)
+	if kind == "" {
+		return
+	}
+	prefs, err := a.preferencesFor(username)
+	if err != nil {
+		log.Printf("Ошибка получения настроек %s: %v", username, err)
+		return
+	}
+	if !prefs.allows(kind, channelName, now) {
+		return
+	}
+	if !a.notifications.markNotified(msg.ID, now) {
+		return
+	}
+
+	notification := Notification{ID: msg.ID, Kind: kind, Channel: channelName, Body: truncate(msg.Text, 100), Message: msg}
+	switch kind {
+	case notificationMention:
+		notification.Title = fmt.Sprintf("%s упомянул вас в #%s", msg.User, channelName)
+	case notificationDirect:
+		notification.Title = fmt.Sprintf("Личное сообщение от %s", msg.User)
+	default:
+		notification.Title = fmt.Sprintf("#%s: %s", channelName, msg.User)
+	}
+	a.emitEvent("notification", notification)
+}
+
+// emitEvent отправляет событие во фронтенд Wails. Без контекста Wails
+// (тесты, headless-запуск) событие пропускается.
+func (a *App) emitEvent(name string, data interface{}) {
+	if a.ctx == nil {
+		return
+	}
+	runtime.EventsEmit(a.ctx, name, data)
+}
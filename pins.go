@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// maxPinsPerChannel - сколько сообщений можно закрепить в одном канале
+const maxPinsPerChannel = 50
+
+func pinOrder(pins []Pin) []string {
+	order := make([]string, 0, len(pins))
+	for _, pin := range pins {
+		order = append(order, pin.MessageID)
+	}
+	return order
+}
+
+func pinIndex(pins []Pin, messageID string) int {
+	for i, pin := range pins {
+		if pin.MessageID == messageID {
+			return i
+		}
+	}
+	return -1
+}
+
+// PinMessage закрепляет сообщение или пост канала. Новое закрепление
+// встаёт в начало списка, порядок можно поменять через ReorderPins.
+func (a *App) PinMessage(channel, messageID, username string) error {
+	if err := a.checkChannelAccess(channel, username); err != nil {
+		return err
+	}
+	if _, _, ok := splitThreadChannel(channel); ok {
+		return fmt.Errorf("ответ в треде нельзя закрепить")
+	}
+
+	a.pinsMu.Lock()
+	defer a.pinsMu.Unlock()
+
+	pins, err := a.store.GetPins(channel)
+	if err != nil {
+		return fmt.Errorf("не удалось получить закреплённые сообщения: %v", err)
+	}
+	if pinIndex(pins, messageID) >= 0 {
+		return fmt.Errorf("сообщение уже закреплено")
+	}
+	if len(pins) >= maxPinsPerChannel {
+		return fmt.Errorf("в канале можно закрепить не больше %d сообщений", maxPinsPerChannel)
+	}
+
+	if _, err := a.store.GetMessage(channel, messageID); err != nil {
+		if err == ErrNotFound {
+			return fmt.Errorf("сообщение не найдено")
+		}
+		return fmt.Errorf("не удалось получить сообщение: %v", err)
+	}
+
+	// Сначала список, потом отметка на сообщении: если отметить не удалось,
+	// список возвращается как был, и сообщение не выглядит закреплённым
+	pin := Pin{MessageID: messageID, PinnedBy: username, PinnedAt: time.Now()}
+	previous := pins
+	pins = append([]Pin{pin}, pins...)
+	if err := a.store.SavePins(channel, pins); err != nil {
+		return fmt.Errorf("не удалось закрепить сообщение: %v", err)
+	}
+
+	_, err = a.store.ModifyMessage(channel, messageID, func(msg *Message) error {
+		msg.PinnedBy = pin.PinnedBy
+		msg.PinnedAt = &pin.PinnedAt
+		return nil
+	})
+	if err != nil {
+		if rollbackErr := a.store.SavePins(channel, previous); rollbackErr != nil {
+			log.Printf("Ошибка отката закрепления %s в #%s: %v", messageID, channel, rollbackErr)
+		}
+		if err == ErrNotFound {
+			return fmt.Errorf("сообщение не найдено")
+		}
+		return fmt.Errorf("не удалось закрепить сообщение: %v", err)
+	}
+
+	a.hub.BroadcastPinUpdate(PinUpdate{Channel: channel, MessageID: messageID, Username: username, Pinned: true, Order: pinOrder(pins)})
+	log.Printf("📌 %s закрепил сообщение %s в #%s", username, messageID, channel)
+	return nil
+}
+
+// UnpinMessage открепляет сообщение канала
+func (a *App) UnpinMessage(channel, messageID, username string) error {
+	if err := a.checkChannelAccess(channel, username); err != nil {
+		return err
+	}
+
+	unpinned, err := a.unpin(channel, messageID, username)
+	if err != nil {
+		return err
+	}
+	if !unpinned {
+		return fmt.Errorf("сообщение не закреплено")
+	}
+	return nil
+}
+
+// unpin убирает сообщение из закреплённых и сообщает об этом каналу.
+// Возвращает false, если сообщение не было закреплено.
+func (a *App) unpin(channel, messageID, username string) (bool, error) {
+	a.pinsMu.Lock()
+	defer a.pinsMu.Unlock()
+
+	pins, err := a.store.GetPins(channel)
+	if err != nil {
+		return false, fmt.Errorf("не удалось получить закреплённые сообщения: %v", err)
+	}
+	i := pinIndex(pins, messageID)
+	if i < 0 {
+		return false, nil
+	}
+
+	pins = append(pins[:i:i], pins[i+1:]...)
+	if err := a.store.SavePins(channel, pins); err != nil {
+		return false, fmt.Errorf("не удалось открепить сообщение: %v", err)
+	}
+
+	// Удалённое сообщение открепляется уже после удаления из истории
+	_, err = a.store.ModifyMessage(channel, messageID, func(msg *Message) error {
+		msg.PinnedBy = ""
+		msg.PinnedAt = nil
+		return nil
+	})
+	if err != nil && err != ErrNotFound {
+		log.Printf("Ошибка снятия отметки закрепления %s: %v", messageID, err)
+	}
+
+	a.hub.BroadcastPinUpdate(PinUpdate{Channel: channel, MessageID: messageID, Username: username, Pinned: false, Order: pinOrder(pins)})
+	log.Printf("📍 %s открепил сообщение %s в #%s", username, messageID, channel)
+	return true, nil
+}
+
+// ReorderPins задаёт новый порядок закреплённых сообщений канала.
+// messageIDs должен содержать все закреплённые сообщения ровно по одному разу.
+func (a *App) ReorderPins(channel, username string, messageIDs []string) error {
+	if err := a.checkChannelAccess(channel, username); err != nil {
+		return err
+	}
+
+	a.pinsMu.Lock()
+	defer a.pinsMu.Unlock()
+
+	pins, err := a.store.GetPins(channel)
+	if err != nil {
+		return fmt.Errorf("не удалось получить закреплённые сообщения: %v", err)
+	}
+	if len(messageIDs) != len(pins) {
+		return fmt.Errorf("список закреплённых сообщений изменился, обновите канал")
+	}
+
+	reordered := make([]Pin, 0, len(pins))
+	for _, messageID := range messageIDs {
+		i := pinIndex(pins, messageID)
+		if i < 0 || pinIndex(reordered, messageID) >= 0 {
+			return fmt.Errorf("список закреплённых сообщений изменился, обновите канал")
+		}
+		reordered = append(reordered, pins[i])
+	}
+	if err := a.store.SavePins(channel, reordered); err != nil {
+		return fmt.Errorf("не удалось сохранить порядок: %v", err)
+	}
+
+	a.hub.BroadcastPinUpdate(PinUpdate{Channel: channel, Username: username, Reordered: true, Order: pinOrder(reordered)})
+	return nil
+}
+
+// GetPinned возвращает закреплённые сообщения канала в заданном порядке
+// вместе с их текущими версиями
+func (a *App) GetPinned(channel, username string) ([]PinnedMessage, error) {
+	if err := a.checkChannelAccess(channel, username); err != nil {
+		return []PinnedMessage{}, err
+	}
+
+	pins, err := a.store.GetPins(channel)
+	if err != nil {
+		return []PinnedMessage{}, fmt.Errorf("не удалось получить закреплённые сообщения: %v", err)
+	}
+
+	pinned := make([]PinnedMessage, 0, len(pins))
+	for _, pin := range pins {
+		msg, err := a.store.GetMessage(channel, pin.MessageID)
+		if err != nil {
+			if err != ErrNotFound {
+				log.Printf("Ошибка получения закреплённого сообщения %s: %v", pin.MessageID, err)
+			}
+			continue
+		}
+		pinned = append(pinned, PinnedMessage{Pin: pin, Message: *msg})
+	}
+	return pinned, nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func pinnedIDs(t *testing.T, app *App, channel, username string) []string {
+	t.Helper()
+	pinned, err := app.GetPinned(channel, username)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []string{}
+	for _, p := range pinned {
+		ids = append(ids, p.Message.ID)
+	}
+	return ids
+}
+
+func TestPinMessages(t *testing.T) {
+	app := newTestApp(t, "al", "bob")
+	first, _ := app.SendMessage("al", "первое", "general")
+	second, _ := app.SendMessage("bob", "второе", "general")
+
+	if err := app.PinMessage("general", first, "al"); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.PinMessage("general", second, "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.PinMessage("general", first, "bob"); err == nil {
+		t.Error("сообщение закреплено дважды")
+	}
+	if err := app.PinMessage("general", "нет-такого", "al"); err == nil {
+		t.Error("закреплено несуществующее сообщение")
+	}
+
+	// новое закрепление встаёт в начало
+	if got := pinnedIDs(t, app, "general", "al"); !reflect.DeepEqual(got, []string{second, first}) {
+		t.Errorf("порядок %v", got)
+	}
+	msg, _ := app.store.GetMessage("general", first)
+	if msg.PinnedBy != "al" || msg.PinnedAt == nil {
+		t.Errorf("отметка закрепления: %q %v", msg.PinnedBy, msg.PinnedAt)
+	}
+
+	if err := app.ReorderPins("general", "al", []string{first}); err == nil {
+		t.Error("принят неполный порядок")
+	}
+	if err := app.ReorderPins("general", "al", []string{first, first}); err == nil {
+		t.Error("принят порядок с повтором")
+	}
+	if err := app.ReorderPins("general", "al", []string{first, second}); err != nil {
+		t.Fatal(err)
+	}
+	if got := pinnedIDs(t, app, "general", "al"); !reflect.DeepEqual(got, []string{first, second}) {
+		t.Errorf("порядок после ReorderPins %v", got)
+	}
+
+	if err := app.UnpinMessage("general", first, "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.UnpinMessage("general", first, "bob"); err == nil {
+		t.Error("откреплено незакреплённое сообщение")
+	}
+	msg, _ = app.store.GetMessage("general", first)
+	if msg.PinnedBy != "" || msg.PinnedAt != nil {
+		t.Error("отметка закрепления осталась после открепления")
+	}
+
+	// удалённое сообщение открепляется
+	if err := app.DeleteMessage(second, "general", "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if got := pinnedIDs(t, app, "general", "al"); len(got) != 0 {
+		t.Errorf("после удаления закреплены %v", got)
+	}
+}
+
+func TestPinRestrictions(t *testing.T) {
+	app := newTestApp(t, "al", "eve")
+	root, _ := app.SendMessage("al", "пост", "general")
+	reply, err := app.SendReply("al", "ответ", "general", root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := app.PinMessage(threadChannel("general", root), reply, "al"); err == nil {
+		t.Error("закреплён ответ в треде")
+	}
+
+	if _, err := app.CreatePrivateChannel("secret", "", "al", nil); err != nil {
+		t.Fatal(err)
+	}
+	secret, _ := app.SendMessage("al", "тайна", "secret")
+	if err := app.PinMessage("secret", secret, "eve"); err == nil {
+		t.Error("посторонний закрепил сообщение приватного канала")
+	}
+	if err := app.PinMessage("secret", secret, "al"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.GetPinned("secret", "eve"); err == nil {
+		t.Error("посторонний видит закреплённые сообщения приватного канала")
+	}
+	if err := app.UnpinMessage("secret", secret, "eve"); err == nil {
+		t.Error("посторонний открепил сообщение приватного канала")
+	}
+	if err := app.ReorderPins("secret", "eve", []string{secret}); err == nil {
+		t.Error("посторонний поменял порядок закреплённых")
+	}
+}
+
+func TestPinLimit(t *testing.T) {
+	app := newTestApp(t, "al")
+	for i := 0; i < maxPinsPerChannel; i++ {
+		id, _ := app.SendMessage("al", "сообщение", "general")
+		if err := app.PinMessage("general", id, "al"); err != nil {
+			t.Fatalf("закрепление %d: %v", i, err)
+		}
+	}
+	extra, _ := app.SendMessage("al", "лишнее", "general")
+	if err := app.PinMessage("general", extra, "al"); err == nil {
+		t.Errorf("закреплено больше %d сообщений", maxPinsPerChannel)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+func defaultPreferences(username string) UserPreferences {
+	return UserPreferences{
+		Username:      username,
+		Notifications: NotificationSettings{Level: notifyAll},
+		Channels:      make(map[string]ChannelPreferences),
+	}
+}
+
+// validNotifyLevel проверяет уровень уведомлений; пустой уровень допустим
+// только в настройках канала, где он означает "как в общих настройках"
+func validNotifyLevel(level string, allowEmpty bool) error {
+	switch level {
+	case notifyAll, notifyMentions, notifyNone:
+		return nil
+	case "":
+		if allowEmpty {
+			return nil
+		}
+	}
+	return fmt.Errorf("неизвестный уровень уведомлений: %s", level)
+}
+
+// dndActive проверяет, включён ли режим "не беспокоить" на момент now
+func (s NotificationSettings) dndActive(now time.Time) bool {
+	return s.DoNotDisturb && (s.DNDUntil == nil || now.Before(*s.DNDUntil))
+}
+
+// mutedAt проверяет, заглушён ли канал на момент now
+func (p ChannelPreferences) mutedAt(now time.Time) bool {
+	return p.Muted && (p.MuteUntil == nil || now.Before(*p.MuteUntil))
+}
+
+// channelLevel возвращает действующий уровень уведомлений канала:
+// заглушённый канал молчит, свой уровень канала важнее общего
+func (p UserPreferences) channelLevel(channel string, now time.Time) string {
+	level := p.Notifications.Level
+	if channelPrefs, exists := p.Channels[channel]; exists {
+		if channelPrefs.mutedAt(now) {
+			return notifyNone
+		}
+		if channelPrefs.Level != "" {
+			level = channelPrefs.Level
+		}
+	}
+	return level
+}
+
+// allows проверяет, можно ли показать уведомление вида kind из канала
+func (p UserPreferences) allows(kind, channel string, now time.Time) bool {
+	if p.Notifications.dndActive(now) {
+		return false
+	}
+	switch p.channelLevel(channel, now) {
+	case notifyNone:
+		return false
+	case notifyMentions:
+		return kind != notificationMessage
+	default:
+		return true
+	}
+}
+
+// preferencesFor возвращает настройки пользователя; у того, кто их ещё
+// не менял, - значения по умолчанию
+func (a *App) preferencesFor(username string) (UserPreferences, error) {
+	prefs, err := a.store.GetPreferences(username)
+	if err == ErrNotFound {
+		return defaultPreferences(username), nil
+	}
+	if err != nil {
+		return UserPreferences{}, fmt.Errorf("не удалось получить настройки: %v", err)
+	}
+	if prefs.Channels == nil {
+		prefs.Channels = make(map[string]ChannelPreferences)
+	}
+	return *prefs, nil
+}
+
+// updatePreferences применяет modify к настройкам пользователя и сохраняет их
+func (a *App) updatePreferences(username string, modify func(prefs *UserPreferences)) (UserPreferences, error) {
+	a.prefsMu.Lock()
+	defer a.prefsMu.Unlock()
+
+	prefs, err := a.preferencesFor(username)
+	if err != nil {
+		return UserPreferences{}, err
+	}
+	modify(&prefs)
+	if err := a.store.SavePreferences(prefs); err != nil {
+		return UserPreferences{}, fmt.Errorf("не удалось сохранить настройки: %v", err)
+	}
+	return prefs, nil
+}
+
+// GetPreferences возвращает общие настройки пользователя и настройки каналов
+func (a *App) GetPreferences(username string) (UserPreferences, error) {
+	return a.preferencesFor(username)
+}
+
+// GetNotificationSettings возвращает общие настройки уведомлений пользователя
+func (a *App) GetNotificationSettings(username string) (NotificationSettings, error) {
+	prefs, err := a.preferencesFor(username)
+	if err != nil {
+		return NotificationSettings{}, err
+	}
+	return prefs.Notifications, nil
+}
+
+// UpdateNotificationSettings сохраняет общие настройки уведомлений пользователя
+func (a *App) UpdateNotificationSettings(username string, settings NotificationSettings) (NotificationSettings, error) {
+	if err := validNotifyLevel(settings.Level, false); err != nil {
+		return NotificationSettings{}, err
+	}
+	if !settings.DoNotDisturb {
+		settings.DNDUntil = nil
+	}
+
+	if _, err := a.updatePreferences(username, func(prefs *UserPreferences) {
+		prefs.Notifications = settings
+	}); err != nil {
+		return NotificationSettings{}, err
+	}
+	log.Printf("🔔 %s обновил настройки уведомлений: %s", username, settings.Level)
+	return settings, nil
+}
+
+// UpdateChannelPreferences сохраняет настройки канала: уровень уведомлений
+// и заглушение (бессрочно или до MuteUntil). Настройки по умолчанию
+// из записи убираются.
+func (a *App) UpdateChannelPreferences(username, channel string, channelPrefs ChannelPreferences) (ChannelPreferences, error) {
+	if err := validNotifyLevel(channelPrefs.Level, true); err != nil {
+		return ChannelPreferences{}, err
+	}
+	if err := a.checkChannelAccess(channel, username); err != nil {
+		return ChannelPreferences{}, err
+	}
+	channelPrefs.Channel = channel
+	if !channelPrefs.Muted {
+		channelPrefs.MuteUntil = nil
+	}
+
+	if _, err := a.updatePreferences(username, func(prefs *UserPreferences) {
+		if channelPrefs.Level == "" && !channelPrefs.Muted {
+			delete(prefs.Channels, channel)
+		} else {
+			prefs.Channels[channel] = channelPrefs
+		}
+	}); err != nil {
+		return ChannelPreferences{}, err
+	}
+
+	if channelPrefs.Muted {
+		log.Printf("🔕 %s заглушил #%s", username, channel)
+	} else {
+		log.Printf("🔔 %s настроил уведомления #%s: %s", username, channel, channelPrefs.Level)
+	}
+	return channelPrefs, nil
+}
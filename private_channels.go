@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// CreatePrivateChannel создаёт приватный канал. Его видят, читают и пишут
+// в него только участники; остальные попадают в канал по приглашению.
+func (a *App) CreatePrivateChannel(name, description, createdBy string, members []string) (Channel, error) {
+	for _, member := range members {
+		if _, exists := userManager.GetUser(member); !exists {
+			return Channel{}, fmt.Errorf("пользователь %s не найден", member)
+		}
+	}
+	return a.createChannel(name, description, createdBy, true, members)
+}
+
+// InviteToChannel приглашает пользователя в приватный канал. Приглашать
+// могут только участники канала; приглашённый входит через JoinChannel.
+func (a *App) InviteToChannel(channelName, inviter, invitee string) error {
+	channel, err := a.store.GetChannel(channelName)
+	if err != nil {
+		return fmt.Errorf("канал не найден")
+	}
+	if !channel.IsPrivate || channel.IsDirect {
+		return fmt.Errorf("приглашения нужны только в приватные каналы")
+	}
+	if !contains(channel.Members, inviter) {
+		return fmt.Errorf("приглашать могут только участники канала")
+	}
+	if contains(channel.Members, invitee) {
+		return fmt.Errorf("%s уже участник #%s", invitee, channelName)
+	}
+	if _, exists := userManager.GetUser(invitee); !exists {
+		return fmt.Errorf("пользователь %s не найден", invitee)
+	}
+
+	invitation := Invitation{Channel: channelName, Invitee: invitee, InvitedBy: inviter, CreatedAt: time.Now()}
+	if err := a.store.SaveInvitation(invitation); err != nil {
+		return fmt.Errorf("не удалось сохранить приглашение: %v", err)
+	}
+
+	a.hub.SendToUser(invitee, WSMessage{
+		Type:    "channel_invitation",
+		Payload: invitation,
+	})
+	log.Printf("✉️ %s пригласил %s в #%s", inviter, invitee, channelName)
+	return nil
+}
+
+// GetInvitations возвращает приглашения пользователя в приватные каналы
+func (a *App) GetInvitations(username string) ([]Invitation, error) {
+	invitations, err := a.store.GetInvitations(username)
+	if err != nil {
+		log.Printf("Ошибка получения приглашений %s: %v", username, err)
+		return []Invitation{}, nil
+	}
+	return invitations, nil
+}
+
+// DeclineInvitation отклоняет приглашение в канал
+func (a *App) DeclineInvitation(channelName, username string) error {
+	return a.store.DeleteInvitation(channelName, username)
+}
+
+func (a *App) hasInvitation(channelName, username string) bool {
+	invitations, err := a.store.GetInvitations(username)
+	if err != nil {
+		return false
+	}
+	for _, invitation := range invitations {
+		if invitation.Channel == channelName {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package main
+
+import "testing"
+
+func TestPrivateChannelAccess(t *testing.T) {
+	app := newTestApp(t, "al", "bob", "eve")
+	if _, err := app.CreatePrivateChannel("secret", "", "al", []string{"bob"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.SendMessage("al", "только для своих", "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := app.GetMessages("secret", "bob"); err != nil {
+		t.Errorf("участник не может читать: %v", err)
+	}
+	if _, err := app.GetMessages("secret", "eve"); err == nil {
+		t.Error("посторонний читает приватный канал")
+	}
+	if _, err := app.SendMessage("eve", "привет", "secret"); err == nil {
+		t.Error("посторонний пишет в приватный канал")
+	}
+	channels, _ := app.GetChannels("eve")
+	for _, channel := range channels {
+		if channel.Name == "secret" {
+			t.Error("посторонний видит приватный канал в списке")
+		}
+	}
+}
+
+func TestPrivateChannelInvitation(t *testing.T) {
+	app := newTestApp(t, "al", "bob", "eve")
+	if _, err := app.CreatePrivateChannel("secret", "", "al", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := app.JoinChannel("secret", "bob"); err == nil {
+		t.Error("вход в приватный канал без приглашения")
+	}
+	if err := app.InviteToChannel("secret", "eve", "bob"); err == nil {
+		t.Error("пригласил не участник канала")
+	}
+	if err := app.InviteToChannel("secret", "al", "bob"); err != nil {
+		t.Fatalf("InviteToChannel: %v", err)
+	}
+	if err := app.JoinChannel("secret", "bob"); err != nil {
+		t.Fatalf("вход по приглашению: %v", err)
+	}
+	if _, err := app.GetMessages("secret", "bob"); err != nil {
+		t.Errorf("приглашённый не может читать: %v", err)
+	}
+	if invitations, _ := app.GetInvitations("bob"); len(invitations) != 0 {
+		t.Errorf("приглашение не удалено после входа: %v", invitations)
+	}
+}
+
+// Удалённый канал недоступен, а созданный заново под тем же именем
+// не показывает прежнюю переписку
+func TestDeletedChannelAccess(t *testing.T) {
+	app := newTestApp(t, "al", "bob", "eve")
+	if _, err := app.CreatePrivateChannel("secret", "", "al", []string{"bob"}); err != nil {
+		t.Fatal(err)
+	}
+	rootID, err := app.SendMessage("al", "старая тайна", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.SendReply("bob", "ответ в треде", "secret", rootID); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.InviteToChannel("secret", "al", "eve"); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.DeleteChannel("secret", "al"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := app.GetMessages("secret", "eve"); err == nil {
+		t.Error("чтение удалённого канала")
+	}
+	if _, err := app.GetThread("secret", rootID, "eve"); err == nil {
+		t.Error("чтение треда удалённого канала")
+	}
+	if _, err := app.SendMessage("eve", "привет", "secret"); err == nil {
+		t.Error("запись в удалённый канал")
+	}
+
+	if _, err := app.CreatePrivateChannel("secret", "", "al", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.GetMessages("secret", "eve"); err == nil {
+		t.Error("посторонний читает канал, созданный заново")
+	}
+	if _, err := app.SendMessage("eve", "привет", "secret"); err == nil {
+		t.Error("посторонний пишет в канал, созданный заново")
+	}
+	if err := app.JoinChannel("secret", "eve"); err == nil {
+		t.Error("приглашение в удалённый канал открыло новый")
+	}
+	messages, err := app.GetMessages("secret", "al")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 0 {
+		t.Errorf("в новом канале видна старая переписка: %v", messageIDs(messages))
+	}
+	if _, err := app.GetThread("secret", rootID, "al"); err == nil {
+		t.Error("в новом канале открывается старый тред")
+	}
+}
+
+// Писать в личную переписку можно только после OpenDirectMessage
+func TestDirectMessageRequiresChannel(t *testing.T) {
+	app := newTestApp(t, "al", "bob", "eve")
+	name := directChannelName([]string{"al", "bob"})
+
+	if _, err := app.SendMessage("eve", "подслушаю", name); err == nil {
+		t.Error("запись в несозданную личную переписку")
+	}
+	if _, err := app.OpenDirectMessage("al", []string{"bob"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.GetMessages(name, "eve"); err == nil {
+		t.Error("посторонний читает личную переписку")
+	}
+	if _, err := app.SendMessage("bob", "привет", name); err != nil {
+		t.Errorf("участник не может писать: %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// maxUnreadCount - больше этого непрочитанных в канале не считаем,
+// клиент показывает "99+" по флагу HasMore
+const maxUnreadCount = 100
+
+// MarkRead отмечает канал прочитанным до messageID. Отметка двигается
+// только вперёд, остальные устройства пользователя получают read_marker.
+func (a *App) MarkRead(channel, username, messageID string) (ReadMarker, error) {
+	if err := a.checkChannelAccess(channel, username); err != nil {
+		return ReadMarker{}, err
+	}
+
+	msg, err := a.store.GetMessage(channel, messageID)
+	if err == ErrNotFound {
+		return ReadMarker{}, fmt.Errorf("сообщение не найдено")
+	}
+	if err != nil {
+		return ReadMarker{}, fmt.Errorf("не удалось получить сообщение: %v", err)
+	}
+
+	a.markersMu.Lock()
+	defer a.markersMu.Unlock()
+
+	markers, err := a.store.GetReadMarkers(username)
+	if err != nil {
+		return ReadMarker{}, fmt.Errorf("не удалось получить отметки прочтения: %v", err)
+	}
+	if current, exists := markers[channel]; exists && !msg.Timestamp.After(current.Timestamp) {
+		return current, nil
+	}
+
+	marker := ReadMarker{
+		Channel:   channel,
+		Username:  username,
+		MessageID: msg.ID,
+		Timestamp: msg.Timestamp,
+		UpdatedAt: time.Now(),
+	}
+	if err := a.store.SaveReadMarker(marker); err != nil {
+		return ReadMarker{}, fmt.Errorf("не удалось сохранить отметку прочтения: %v", err)
+	}
+
+	a.hub.SendToUser(username, WSMessage{Type: "read_marker", Payload: marker})
+	return marker, nil
+}
+
+// GetUnreadCounts возвращает непрочитанные сообщения и упоминания во всех
+// доступных пользователю каналах и личных переписках. Собственные сообщения
+// пользователя не считаются.
+func (a *App) GetUnreadCounts(username string) ([]UnreadCount, error) {
+	channels, err := a.store.GetAllChannels()
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить каналы: %v", err)
+	}
+	markers, err := a.store.GetReadMarkers(username)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить отметки прочтения: %v", err)
+	}
+	prefs, err := a.preferencesFor(username)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+
+	counts := []UnreadCount{}
+	for _, channel := range channels {
+		if channel.IsPrivate && !contains(channel.Members, username) {
+			continue
+		}
+
+		query := HistoryQuery{Limit: maxUnreadCount}
+		if marker, exists := markers[channel.Name]; exists {
+			query.After = marker.Timestamp.Format(time.RFC3339Nano)
+		}
+		page, err := a.store.GetMessageHistory(channel.Name, query)
+		if err != nil {
+			log.Printf("Ошибка подсчёта непрочитанных в #%s: %v", channel.Name, err)
+			continue
+		}
+
+		count := UnreadCount{Channel: channel.Name, HasMore: page.HasMore, Muted: prefs.Channels[channel.Name].mutedAt(now)}
+		for _, msg := range page.Messages {
+			if msg.User == username {
+				continue
+			}
+			count.Unread++
+			if mentionsUser(msg, username) {
+				count.Mentions++
+			}
+		}
+		counts = append(counts, count)
+	}
+	return counts, nil
+}
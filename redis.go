@@ -5,49 +5,65 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 )
 
 var ctx = context.Background()
-var redisClient *redis.Client
 
-func initRedis() {
-	redisClient = redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+// RedisStore - реализация Store поверх Redis
+type RedisStore struct {
+	client *redis.Client
+}
+
+func NewRedisStore(addr, password string, db int) *RedisStore {
+	client := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: password,
+		DB:       db,
 	})
 
-	_, err := redisClient.Ping(ctx).Result()
+	_, err := client.Ping(ctx).Result()
 	if err != nil {
 		fmt.Println("Не удалось подключиться к Redis:", err)
 	} else {
 		fmt.Println("✓ Успешно подключено к Redis")
 	}
+
+	return &RedisStore{client: client}
 }
 
-func SaveChannel(channel Channel) error {
+// notFound приводит redis.Nil к ErrNotFound
+func notFound(err error) error {
+	if err == redis.Nil {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *RedisStore) SaveChannel(channel Channel) error {
 	data, err := json.Marshal(channel)
 	if err != nil {
 		return err
 	}
 
 	key := fmt.Sprintf("channel:%s", channel.Name)
-	err = redisClient.Set(ctx, key, data, 0).Err()
+	err = s.client.Set(ctx, key, data, 0).Err()
 	if err != nil {
 		return err
 	}
 
-	return redisClient.SAdd(ctx, "channels", channel.Name).Err()
+	return s.client.SAdd(ctx, "channels", channel.Name).Err()
 }
 
-func GetChannel(name string) (*Channel, error) {
+func (s *RedisStore) GetChannel(name string) (*Channel, error) {
 	key := fmt.Sprintf("channel:%s", name)
-	data, err := redisClient.Get(ctx, key).Result()
+	data, err := s.client.Get(ctx, key).Result()
 	if err != nil {
-		return nil, err
+		return nil, notFound(err)
 	}
 
 	var channel Channel
@@ -58,15 +74,15 @@ func GetChannel(name string) (*Channel, error) {
 	return &channel, nil
 }
 
-func GetAllChannels() ([]Channel, error) {
-	channelNames, err := redisClient.SMembers(ctx, "channels").Result()
+func (s *RedisStore) GetAllChannels() ([]Channel, error) {
+	channelNames, err := s.client.SMembers(ctx, "channels").Result()
 	if err != nil {
 		return nil, err
 	}
 
 	channels := make([]Channel, 0, len(channelNames))
 	for _, name := range channelNames {
-		channel, err := GetChannel(name)
+		channel, err := s.GetChannel(name)
 		if err == nil {
 			channels = append(channels, *channel)
 		}
@@ -75,30 +91,195 @@ func GetAllChannels() ([]Channel, error) {
 	return channels, nil
 }
 
-func DeleteChannel(name string) error {
-	err := redisClient.SRem(ctx, "channels", name).Err()
+func (s *RedisStore) DeleteChannel(name string) error {
+	err := s.client.SRem(ctx, "channels", name).Err()
 	if err != nil {
 		return err
 	}
 
 	key := fmt.Sprintf("channel:%s", name)
-	return redisClient.Del(ctx, key).Err()
+	return s.client.Del(ctx, key).Err()
+}
+
+// invitationsKey - хеш "канал -> JSON приглашения" пользователя
+func invitationsKey(username string) string {
+	return fmt.Sprintf("invitations:%s", username)
+}
+
+func (s *RedisStore) SaveInvitation(invitation Invitation) error {
+	data, err := json.Marshal(invitation)
+	if err != nil {
+		return err
+	}
+
+	return s.client.HSet(ctx, invitationsKey(invitation.Invitee), invitation.Channel, data).Err()
+}
+
+func (s *RedisStore) GetInvitations(username string) ([]Invitation, error) {
+	result, err := s.client.HGetAll(ctx, invitationsKey(username)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	invitations := make([]Invitation, 0, len(result))
+	for _, data := range result {
+		var invitation Invitation
+		if err := json.Unmarshal([]byte(data), &invitation); err == nil {
+			invitations = append(invitations, invitation)
+		}
+	}
+	return invitations, nil
+}
+
+func (s *RedisStore) DeleteInvitation(channel, username string) error {
+	return s.client.HDel(ctx, invitationsKey(username), channel).Err()
+}
+
+func messagesKey(channel string) string {
+	return fmt.Sprintf("channel:%s:messages", channel)
+}
+
+// messageIndexKey - хеш "ID сообщения -> позиция в списке канала"
+func messageIndexKey(channel string) string {
+	return fmt.Sprintf("channel:%s:messages:index", channel)
+}
+
+// messageIndexedKey отмечает, что индекс канала полностью построен
+func messageIndexedKey(channel string) string {
+	return fmt.Sprintf("channel:%s:messages:indexed", channel)
 }
 
-func SaveMessage(msg Message) error {
+// saveMessageScript добавляет сообщение в список и записывает его позицию
+// в индекс одной атомарной операцией
+var saveMessageScript = redis.NewScript(`
+local length = redis.call('RPUSH', KEYS[1], ARGV[2])
+redis.call('HSET', KEYS[2], ARGV[1], length - 1)
+return length
+`)
+
+// updateMessageScript заменяет сообщение по позиции из индекса.
+// Возвращает 0, если сообщения нет в индексе.
+var updateMessageScript = redis.NewScript(`
+local pos = redis.call('HGET', KEYS[2], ARGV[1])
+if not pos then
+	return 0
+end
+redis.call('LSET', KEYS[1], pos, ARGV[2])
+return 1
+`)
+
+func (s *RedisStore) SaveMessage(msg Message) error {
 	data, err := json.Marshal(msg)
 	if err != nil {
 		return err
 	}
 
-	key := fmt.Sprintf("channel:%s:messages", msg.Channel)
-	return redisClient.RPush(ctx, key, data).Err()
+	keys := []string{messagesKey(msg.Channel), messageIndexKey(msg.Channel)}
+	return saveMessageScript.Run(ctx, s.client, keys, msg.ID, data).Err()
+}
+
+// GetMessage возвращает сообщение по ID через индекс позиций
+func (s *RedisStore) GetMessage(channel, messageID string) (*Message, error) {
+	pos, err := s.messagePosition(channel, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := s.client.LIndex(ctx, messagesKey(channel), pos).Result()
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	var msg Message
+	if err := json.Unmarshal([]byte(data), &msg); err != nil {
+		return nil, err
+	}
+	if msg.ID != messageID {
+		return nil, fmt.Errorf("индекс сообщений канала #%s повреждён", channel)
+	}
+
+	messages := []Message{msg}
+	if err := s.attachReactions(messages); err != nil {
+		return nil, err
+	}
+
+	return &messages[0], nil
+}
+
+// messagePosition ищет позицию сообщения в индексе. Сообщения, сохранённые
+// до появления индекса, находятся полным проходом по списку один раз,
+// после чего индекс канала перестраивается.
+func (s *RedisStore) messagePosition(channel, messageID string) (int64, error) {
+	pos, err := s.client.HGet(ctx, messageIndexKey(channel), messageID).Int64()
+	if err == nil {
+		return pos, nil
+	}
+	if err != redis.Nil {
+		return 0, err
+	}
+
+	indexed, err := s.client.Exists(ctx, messageIndexedKey(channel)).Result()
+	if err != nil {
+		return 0, err
+	}
+	if indexed > 0 {
+		return 0, ErrNotFound
+	}
+
+	if err := s.rebuildMessageIndex(channel); err != nil {
+		return 0, err
+	}
+
+	pos, err = s.client.HGet(ctx, messageIndexKey(channel), messageID).Int64()
+	return pos, notFound(err)
 }
 
-func GetMessages(channel string, limit int64) ([]Message, error) {
-	key := fmt.Sprintf("channel:%s:messages", channel)
+// rebuildIndexLua заново строит индекс позиций KEYS[2] по списку KEYS[1]
+// и ставит отметку KEYS[3]. Используется внутри скриптов, чтобы перестроение
+// было атомарным и не теряло сообщения, добавленные в это время.
+const rebuildIndexLua = `
+local items = redis.call('LRANGE', KEYS[1], 0, -1)
+redis.call('DEL', KEYS[2])
+for i, item in ipairs(items) do
+	if item ~= 'DELETED' then
+		local ok, msg = pcall(cjson.decode, item)
+		if ok and type(msg) == 'table' and msg.id then
+			redis.call('HSET', KEYS[2], msg.id, i - 1)
+		end
+	end
+end
+redis.call('SET', KEYS[3], 1)
+`
+
+// rebuildMessageIndexScript дополнительно учитывает надгробия, оставшиеся
+// в старых данных, чтобы их убрало фоновое уплотнение
+var rebuildMessageIndexScript = redis.NewScript(rebuildIndexLua + `
+local tombstones = 0
+for _, item in ipairs(items) do
+	if item == 'DELETED' then
+		tombstones = tombstones + 1
+	end
+end
+if tombstones > 0 then
+	redis.call('SET', KEYS[4], tombstones)
+	redis.call('SADD', KEYS[5], ARGV[1])
+end
+return #items
+`)
+
+func (s *RedisStore) rebuildMessageIndex(channel string) error {
+	keys := []string{
+		messagesKey(channel),
+		messageIndexKey(channel),
+		messageIndexedKey(channel),
+		tombstonesKey(channel),
+		pendingCompactionKey,
+	}
+	return rebuildMessageIndexScript.Run(ctx, s.client, keys, channel).Err()
+}
 
-	result, err := redisClient.LRange(ctx, key, -limit, -1).Result()
+func (s *RedisStore) GetMessages(channel string, limit int64) ([]Message, error) {
+	result, err := s.client.LRange(ctx, messagesKey(channel), -limit, -1).Result()
 	if err != nil {
 		return nil, err
 	}
@@ -115,55 +296,635 @@ func GetMessages(channel string, limit int64) ([]Message, error) {
 		}
 	}
 
+	if err := s.attachReactions(messages); err != nil {
+		return nil, err
+	}
+
 	return messages, nil
 }
 
-func UpdateMessage(channel string, updatedMsg Message) error {
-	key := fmt.Sprintf("channel:%s:messages", channel)
+func (s *RedisStore) GetMessageHistory(channel string, query HistoryQuery) (MessagePage, error) {
+	length, err := s.client.LLen(ctx, messagesKey(channel)).Result()
+	if err != nil {
+		return MessagePage{}, err
+	}
+
+	var cursorPos int64
+	if cursor := query.Before + query.After; cursor != "" {
+		cursorPos, err = s.cursorPosition(channel, cursor, query.After != "", length)
+		if err != nil {
+			return MessagePage{}, err
+		}
+	}
+
+	start, end := historyRange(query, length, cursorPos)
+	messages := []Message{}
+	// Удалённые сообщения остаются в списке надгробиями "DELETED" до
+	// компактификации, поэтому окно может оказаться пустым или неполным.
+	// Дочитываем следующие окна, пока страница не заполнится или список
+	// не кончится: start и end в итоге охватывают всё прочитанное.
+	for start <= end {
+		result, err := s.client.LRange(ctx, messagesKey(channel), start, end).Result()
+		if err != nil {
+			return MessagePage{}, err
+		}
+
+		window := make([]Message, 0, len(result))
+		for _, data := range result {
+			if data == "DELETED" {
+				continue
+			}
+
+			var msg Message
+			if err := json.Unmarshal([]byte(data), &msg); err == nil {
+				window = append(window, msg)
+			}
+		}
+
+		need := query.Limit - int64(len(messages)+len(window))
+		if query.After != "" {
+			messages = append(messages, window...)
+			if need <= 0 || end >= length-1 {
+				break
+			}
+			start, end = end+1, min(end+need, length-1)
+		} else {
+			messages = append(window, messages...)
+			if need <= 0 || start == 0 {
+				break
+			}
+			start, end = max(start-need, 0), start-1
+		}
+	}
+
+	if err := s.attachReactions(messages); err != nil {
+		return MessagePage{}, err
+	}
+
+	return newMessagePage(messages, query, start, end, length), nil
+}
+
+// cursorPosition переводит курсор в позицию списка: для Before - позиция
+// самого курсора, для After - первая позиция после него
+func (s *RedisStore) cursorPosition(channel, cursor string, after bool, length int64) (int64, error) {
+	messageID, at, isTime := parseCursor(cursor)
+	if !isTime {
+		pos, err := s.messagePosition(channel, messageID)
+		if err != nil {
+			return 0, err
+		}
+		if after {
+			pos++
+		}
+		return pos, nil
+	}
+
+	// Сообщения лежат в списке в порядке отправки, поэтому ищем бинарным поиском
+	var searchErr error
+	pos := sort.Search(int(length), func(i int) bool {
+		ts, ok, err := s.timestampFrom(channel, int64(i), length)
+		if err != nil {
+			searchErr = err
+			return true
+		}
+		if !ok {
+			return true
+		}
+		if after {
+			return ts.After(at)
+		}
+		return !ts.Before(at)
+	})
+
+	return int64(pos), searchErr
+}
+
+// timestampFrom возвращает время первого не удалённого сообщения, начиная
+// с позиции pos. ok == false, если до конца списка остались только надгробия.
+func (s *RedisStore) timestampFrom(channel string, pos, length int64) (time.Time, bool, error) {
+	for ; pos < length; pos++ {
+		data, err := s.client.LIndex(ctx, messagesKey(channel), pos).Result()
+		if err != nil {
+			return time.Time{}, false, err
+		}
+		if data == "DELETED" {
+			continue
+		}
+
+		var msg Message
+		if err := json.Unmarshal([]byte(data), &msg); err != nil {
+			continue
+		}
+		return msg.Timestamp, true, nil
+	}
+
+	return time.Time{}, false, nil
+}
+
+func (s *RedisStore) UpdateMessage(channel string, updatedMsg Message) error {
+	// Проверяем через индекс, что сообщение существует (и достраиваем индекс
+	// для старых данных)
+	if _, err := s.messagePosition(channel, updatedMsg.ID); err != nil {
+		if err == ErrNotFound {
+			return fmt.Errorf("сообщение с ID %s не найдено", updatedMsg.ID)
+		}
+		return err
+	}
+
+	updatedData, err := json.Marshal(updatedMsg)
+	if err != nil {
+		return err
+	}
 
-	messages, err := redisClient.LRange(ctx, key, 0, -1).Result()
+	keys := []string{messagesKey(channel), messageIndexKey(channel)}
+	updated, err := updateMessageScript.Run(ctx, s.client, keys, updatedMsg.ID, updatedData).Int()
 	if err != nil {
 		return err
 	}
+	if updated == 0 {
+		return fmt.Errorf("сообщение с ID %s не найдено", updatedMsg.ID)
+	}
 
-	for i, msgData := range messages {
-		if msgData == "DELETED" {
-			continue
+	return nil
+}
+
+// tombstonesKey - счётчик надгробий в списке канала, ожидающих уплотнения
+func tombstonesKey(channel string) string {
+	return fmt.Sprintf("channel:%s:messages:tombstones", channel)
+}
+
+// pendingCompactionKey - множество каналов и тредов, в которых есть надгробия
+const pendingCompactionKey = "channels:pending_compaction"
+
+// deleteMessageScript заменяет сообщение надгробием "DELETED", не сдвигая
+// позиции остальных сообщений, и удаляет его из индекса и реакции
+var deleteMessageScript = redis.NewScript(`
+local pos = redis.call('HGET', KEYS[2], ARGV[1])
+if not pos then
+	return 0
+end
+redis.call('LSET', KEYS[1], pos, 'DELETED')
+redis.call('HDEL', KEYS[2], ARGV[1])
+redis.call('INCR', KEYS[4])
+redis.call('DEL', KEYS[5], KEYS[6])
+redis.call('SADD', KEYS[7], ARGV[2])
+return 1
+`)
+
+// compactMessagesScript убирает надгробия из списка и перестраивает индекс
+// позиций в той же атомарной операции
+var compactMessagesScript = redis.NewScript(`
+local removed = redis.call('LREM', KEYS[1], 0, 'DELETED')
+if removed > 0 then
+` + rebuildIndexLua + `
+end
+redis.call('DEL', KEYS[4])
+redis.call('SREM', KEYS[5], ARGV[1])
+return removed
+`)
+
+func (s *RedisStore) DeleteMessage(channel, messageID string) error {
+	// Достраиваем индекс для старых данных
+	if _, err := s.messagePosition(channel, messageID); err != nil {
+		return err
+	}
+
+	keys := []string{
+		messagesKey(channel),
+		messageIndexKey(channel),
+		messageIndexedKey(channel),
+		tombstonesKey(channel),
+		reactionsKey(messageID),
+		reactionsSeededKey(messageID),
+		pendingCompactionKey,
+	}
+	deleted, err := deleteMessageScript.Run(ctx, s.client, keys, messageID, channel).Int()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *RedisStore) CompactMessages(channel string) (int64, error) {
+	tombstones, err := s.client.Get(ctx, tombstonesKey(channel)).Int64()
+	if err == redis.Nil || (err == nil && tombstones == 0) {
+		return 0, s.client.SRem(ctx, pendingCompactionKey, channel).Err()
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	keys := []string{
+		messagesKey(channel),
+		messageIndexKey(channel),
+		messageIndexedKey(channel),
+		tombstonesKey(channel),
+		pendingCompactionKey,
+	}
+	return compactMessagesScript.Run(ctx, s.client, keys, channel).Int64()
+}
+
+func (s *RedisStore) PendingCompaction() ([]string, error) {
+	return s.client.SMembers(ctx, pendingCompactionKey).Result()
+}
+
+// maxModifyRetries ограничивает число повторов оптимистичной транзакции
+const maxModifyRetries = 10
+
+// ModifyMessage изменяет сообщение в оптимистичной транзакции WATCH/MULTI.
+// Если список канала или индекс изменились во время чтения, попытка повторяется.
+func (s *RedisStore) ModifyMessage(channel, messageID string, modify func(msg *Message) error) (*Message, error) {
+	var updated Message
+	txf := func(tx *redis.Tx) error {
+		pos, err := s.messagePosition(channel, messageID)
+		if err != nil {
+			return err
+		}
+
+		data, err := tx.LIndex(ctx, messagesKey(channel), pos).Result()
+		if err != nil {
+			return notFound(err)
 		}
 
 		var msg Message
-		if err := json.Unmarshal([]byte(msgData), &msg); err != nil {
+		if err := json.Unmarshal([]byte(data), &msg); err != nil {
+			return err
+		}
+		if msg.ID != messageID {
+			return fmt.Errorf("индекс сообщений канала #%s повреждён", channel)
+		}
+		if err := modify(&msg); err != nil {
+			return err
+		}
+
+		updatedData, err := json.Marshal(msg)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.LSet(ctx, messagesKey(channel), pos, updatedData)
+			return nil
+		})
+		updated = msg
+		return err
+	}
+
+	for i := 0; i < maxModifyRetries; i++ {
+		err := s.client.Watch(ctx, txf, messagesKey(channel), messageIndexKey(channel))
+		if err == redis.TxFailedErr {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		messages := []Message{updated}
+		if err := s.attachReactions(messages); err != nil {
+			return nil, err
+		}
+		return &messages[0], nil
+	}
+
+	return nil, fmt.Errorf("не удалось изменить сообщение %s: слишком много конкурентных изменений", messageID)
+}
+
+// reactionsKey - хеш "emoji -> JSON-список пользователей" сообщения.
+// Реакции хранятся отдельно от JSON сообщения, чтобы переключать их атомарно.
+func reactionsKey(messageID string) string {
+	return fmt.Sprintf("message:%s:reactions", messageID)
+}
+
+// reactionsSeededKey отмечает, что реакции из JSON сообщения (записанные до
+// появления хеша) уже перенесены в хеш и хеш является источником истины
+func reactionsSeededKey(messageID string) string {
+	return fmt.Sprintf("message:%s:reactions:seeded", messageID)
+}
+
+// toggleReactionScript переключает реакцию пользователя и возвращает все
+// реакции сообщения в виде плоского списка HGETALL. Сообщение ищется через
+// индекс в том же скрипте, поэтому удалённое или ещё не найденное
+// сообщение (nil) не получит реакцию, а удаление не вернёт её обратно.
+var toggleReactionScript = redis.NewScript(`
+local pos = redis.call('HGET', KEYS[2], ARGV[1])
+if not pos then
+	return false
+end
+local data = redis.call('LINDEX', KEYS[1], pos)
+if not data or data == 'DELETED' then
+	return false
+end
+local ok, msg = pcall(cjson.decode, data)
+if not ok or type(msg) ~= 'table' or msg.id ~= ARGV[1] then
+	return redis.error_reply('индекс сообщений канала повреждён')
+end
+
+if redis.call('EXISTS', KEYS[4]) == 0 then
+	if type(msg.reactions) == 'table' then
+		for emoji, users in pairs(msg.reactions) do
+			if type(users) == 'table' and #users > 0 then
+				redis.call('HSET', KEYS[3], emoji, cjson.encode(users))
+			end
+		end
+	end
+	redis.call('SET', KEYS[4], 1)
+end
+
+local raw = redis.call('HGET', KEYS[3], ARGV[2])
+local result = {}
+local found = false
+if raw then
+	for _, user in ipairs(cjson.decode(raw)) do
+		if user == ARGV[3] then
+			found = true
+		else
+			table.insert(result, user)
+		end
+	end
+end
+if not found then
+	table.insert(result, ARGV[3])
+end
+
+if #result == 0 then
+	redis.call('HDEL', KEYS[3], ARGV[2])
+else
+	redis.call('HSET', KEYS[3], ARGV[2], cjson.encode(result))
+end
+return redis.call('HGETALL', KEYS[3])
+`)
+
+func (s *RedisStore) ToggleReaction(channel, messageID, emoji, username string) (map[string][]string, error) {
+	// Достраиваем индекс для старых данных
+	if _, err := s.messagePosition(channel, messageID); err != nil {
+		return nil, err
+	}
+
+	keys := []string{
+		messagesKey(channel),
+		messageIndexKey(channel),
+		reactionsKey(messageID),
+		reactionsSeededKey(messageID),
+	}
+	fields, err := toggleReactionScript.Run(ctx, s.client, keys, messageID, emoji, username).StringSlice()
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	return decodeReactions(fields), nil
+}
+
+// decodeReactions разбирает плоский ответ HGETALL хеша реакций
+func decodeReactions(fields []string) map[string][]string {
+	reactions := make(map[string][]string, len(fields)/2)
+	for i := 0; i+1 < len(fields); i += 2 {
+		var users []string
+		if err := json.Unmarshal([]byte(fields[i+1]), &users); err == nil && len(users) > 0 {
+			reactions[fields[i]] = users
+		}
+	}
+	return reactions
+}
+
+// attachReactions подставляет в сообщения реакции из хешей одним конвейером.
+// Для сообщений, на которые ещё не реагировали через хеш, остаются реакции из JSON.
+func (s *RedisStore) attachReactions(messages []Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	pipe := s.client.Pipeline()
+	seeded := make([]*redis.IntCmd, len(messages))
+	hashes := make([]*redis.StringStringMapCmd, len(messages))
+	for i, msg := range messages {
+		seeded[i] = pipe.Exists(ctx, reactionsSeededKey(msg.ID))
+		hashes[i] = pipe.HGetAll(ctx, reactionsKey(msg.ID))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return err
+	}
+
+	for i := range messages {
+		if seeded[i].Val() == 0 {
 			continue
 		}
 
-		if msg.ID == updatedMsg.ID {
-			updatedData, err := json.Marshal(updatedMsg)
-			if err != nil {
-				return err
+		reactions := make(map[string][]string, len(hashes[i].Val()))
+		for emoji, data := range hashes[i].Val() {
+			var users []string
+			if err := json.Unmarshal([]byte(data), &users); err == nil && len(users) > 0 {
+				reactions[emoji] = users
 			}
+		}
+		messages[i].Reactions = reactions
+	}
+
+	return nil
+}
+
+func threadFollowersKey(parentID string) string {
+	return fmt.Sprintf("thread:%s:followers", parentID)
+}
+
+func (s *RedisStore) AddThreadFollower(parentID, username string) error {
+	return s.client.SAdd(ctx, threadFollowersKey(parentID), username).Err()
+}
+
+func (s *RedisStore) RemoveThreadFollower(parentID, username string) error {
+	return s.client.SRem(ctx, threadFollowersKey(parentID), username).Err()
+}
+
+func (s *RedisStore) GetThreadFollowers(parentID string) ([]string, error) {
+	return s.client.SMembers(ctx, threadFollowersKey(parentID)).Result()
+}
+
+func (s *RedisStore) Ping() error {
+	return s.client.Ping(ctx).Err()
+}
+
+func readMarkersKey(username string) string {
+	return fmt.Sprintf("read_markers:%s", username)
+}
+
+func (s *RedisStore) SaveReadMarker(marker ReadMarker) error {
+	data, err := json.Marshal(marker)
+	if err != nil {
+		return err
+	}
+	return s.client.HSet(ctx, readMarkersKey(marker.Username), marker.Channel, data).Err()
+}
+
+func (s *RedisStore) GetReadMarkers(username string) (map[string]ReadMarker, error) {
+	values, err := s.client.HGetAll(ctx, readMarkersKey(username)).Result()
+	if err != nil {
+		return nil, err
+	}
 
-			return redisClient.LSet(ctx, key, int64(i), updatedData).Err()
+	markers := make(map[string]ReadMarker, len(values))
+	for channel, data := range values {
+		var marker ReadMarker
+		if err := json.Unmarshal([]byte(data), &marker); err == nil {
+			markers[channel] = marker
 		}
 	}
+	return markers, nil
+}
+
+func pinsKey(channel string) string {
+	return fmt.Sprintf("pins:%s", channel)
+}
+
+func (s *RedisStore) SavePins(channel string, pins []Pin) error {
+	if len(pins) == 0 {
+		return s.client.Del(ctx, pinsKey(channel)).Err()
+	}
+
+	data, err := json.Marshal(pins)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, pinsKey(channel), data, 0).Err()
+}
+
+func (s *RedisStore) GetPins(channel string) ([]Pin, error) {
+	data, err := s.client.Get(ctx, pinsKey(channel)).Result()
+	if err == redis.Nil {
+		return []Pin{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	pins := []Pin{}
+	if err := json.Unmarshal([]byte(data), &pins); err != nil {
+		return nil, err
+	}
+	return pins, nil
+}
+
+func preferencesKey(username string) string {
+	return fmt.Sprintf("preferences:%s", username)
+}
+
+func (s *RedisStore) SavePreferences(prefs UserPreferences) error {
+	data, err := json.Marshal(prefs)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, preferencesKey(prefs.Username), data, 0).Err()
+}
+
+func (s *RedisStore) GetPreferences(username string) (*UserPreferences, error) {
+	data, err := s.client.Get(ctx, preferencesKey(username)).Result()
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	var prefs UserPreferences
+	if err := json.Unmarshal([]byte(data), &prefs); err != nil {
+		return nil, err
+	}
+	return &prefs, nil
+}
+
+func sessionKey(tokenHash string) string {
+	return fmt.Sprintf("session:%s", tokenHash)
+}
+
+func userSessionsKey(username string) string {
+	return fmt.Sprintf("sessions:user:%s", username)
+}
+
+// SaveSession сохраняет сессию с TTL до её истечения, чтобы Redis сам
+// убирал просроченные записи
+func (s *RedisStore) SaveSession(session Session) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	ttl := time.Until(session.ExpiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+
+	pipe := s.client.TxPipeline()
+	pipe.Set(ctx, sessionKey(session.TokenHash), data, ttl)
+	pipe.SAdd(ctx, userSessionsKey(session.Username), session.TokenHash)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (s *RedisStore) GetSession(tokenHash string) (*Session, error) {
+	data, err := s.client.Get(ctx, sessionKey(tokenHash)).Result()
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	var session Session
+	if err := json.Unmarshal([]byte(data), &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (s *RedisStore) DeleteSession(tokenHash string) error {
+	session, err := s.GetSession(tokenHash)
+	if err == ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	pipe := s.client.TxPipeline()
+	pipe.Del(ctx, sessionKey(tokenHash))
+	pipe.SRem(ctx, userSessionsKey(session.Username), tokenHash)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// GetUserSessions возвращает живые сессии пользователя и попутно убирает
+// из индекса хеши сессий, истёкших по TTL
+func (s *RedisStore) GetUserSessions(username string) ([]Session, error) {
+	hashes, err := s.client.SMembers(ctx, userSessionsKey(username)).Result()
+	if err != nil {
+		return nil, err
+	}
 
-	return fmt.Errorf("сообщение с ID %s не найдено", updatedMsg.ID)
+	sessions := []Session{}
+	for _, hash := range hashes {
+		session, err := s.GetSession(hash)
+		if err == ErrNotFound {
+			s.client.SRem(ctx, userSessionsKey(username), hash)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, *session)
+	}
+	return sessions, nil
 }
 
-func SaveUserToRedis(user *User) error {
+func (s *RedisStore) SaveUser(user *User) error {
 	data, err := json.Marshal(user)
 	if err != nil {
 		return err
 	}
 
 	key := fmt.Sprintf("user:%s", user.Email)
-	return redisClient.Set(ctx, key, data, 0).Err()
+	return s.client.Set(ctx, key, data, 0).Err()
 }
 
-func GetUserFromRedis(email string) (*User, error) {
+func (s *RedisStore) GetUser(email string) (*User, error) {
 	key := fmt.Sprintf("user:%s", email)
-	data, err := redisClient.Get(ctx, key).Result()
+	data, err := s.client.Get(ctx, key).Result()
 	if err != nil {
-		return nil, err
+		return nil, notFound(err)
 	}
 
 	var user User
@@ -175,8 +936,8 @@ func GetUserFromRedis(email string) (*User, error) {
 }
 
 // Получаем всех пользователей из Redis
-func GetAllUsersFromRedis() ([]User, error) {
-	keys, err := redisClient.Keys(ctx, "user:*").Result()
+func (s *RedisStore) GetAllUsers() ([]User, error) {
+	keys, err := s.client.Keys(ctx, "user:*").Result()
 	if err != nil {
 		return nil, err
 	}
@@ -196,7 +957,7 @@ func GetAllUsersFromRedis() ([]User, error) {
 			continue
 		}
 
-		data, err := redisClient.Get(ctx, key).Result()
+		data, err := s.client.Get(ctx, key).Result()
 		if err != nil {
 			continue
 		}
@@ -211,14 +972,15 @@ func GetAllUsersFromRedis() ([]User, error) {
 	return users, nil
 }
 
-// SaveUserPasswordToRedis сохраняет хешированный пароль
-func SaveUserPasswordToRedis(email, hashedPassword string) error {
+// SaveUserPassword сохраняет хешированный пароль
+func (s *RedisStore) SaveUserPassword(email, hashedPassword string) error {
 	key := fmt.Sprintf("user:%s:password", email)
-	return redisClient.Set(ctx, key, hashedPassword, 0).Err()
+	return s.client.Set(ctx, key, hashedPassword, 0).Err()
 }
 
-// GetUserPasswordFromRedis получает хешированный пароль
-func GetUserPasswordFromRedis(email string) (string, error) {
+// GetUserPassword получает хешированный пароль
+func (s *RedisStore) GetUserPassword(email string) (string, error) {
 	key := fmt.Sprintf("user:%s:password", email)
-	return redisClient.Get(ctx, key).Result()
+	password, err := s.client.Get(ctx, key).Result()
+	return password, notFound(err)
 }
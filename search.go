@@ -0,0 +1,602 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const (
+	defaultSearchPageSize = 20
+	maxSearchPageSize     = 100
+
+	// параметры BM25
+	bm25K1 = 1.2
+	bm25B  = 0.75
+	// совпадение по началу слова весит меньше точного
+	prefixMatchWeight = 0.5
+)
+
+// SearchQuery - запрос поиска. В Text кроме слов можно писать операторы:
+// in:#канал, from:@автор, after:/before: с датой ГГГГ-ММ-ДД или RFC3339,
+// has:attachment и is:post. Операторы дополняют поля запроса.
+type SearchQuery struct {
+	Text          string `json:"text"`
+	Channel       string `json:"channel,omitempty"`
+	Author        string `json:"author,omitempty"`
+	After         string `json:"after,omitempty"`
+	Before        string `json:"before,omitempty"`
+	HasAttachment bool   `json:"hasAttachment,omitempty"`
+	IsPost        bool   `json:"isPost,omitempty"`
+	Offset        int    `json:"offset"`
+	Limit         int    `json:"limit"`
+}
+
+// TextRange - диапазон символов (не байт) в тексте сообщения
+type TextRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// SearchResult - найденное сообщение и совпадения в его тексте
+type SearchResult struct {
+	Message    Message     `json:"message"`
+	Score      float64     `json:"score"`
+	Highlights []TextRange `json:"highlights"`
+}
+
+// SearchPage - страница результатов, отсортированных по релевантности.
+// Total - число совпадений в индексе; сообщение, удалённое мимо индекса,
+// выпадает из него, когда попадает на страницу.
+type SearchPage struct {
+	Results    []SearchResult `json:"results"`
+	Total      int            `json:"total"`
+	HasMore    bool           `json:"hasMore"`
+	NextOffset int            `json:"nextOffset"`
+}
+
+type searchToken struct {
+	term       string
+	start, end int // в рунах
+}
+
+// tokenize разбивает текст на слова в нижнем регистре (ё приравнивается к е)
+// и запоминает их позиции для подсветки
+func tokenize(text string) []searchToken {
+	tokens := []searchToken{}
+	var word []rune
+	start, pos := 0, 0
+	flush := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, searchToken{term: string(word), start: start, end: pos})
+			word = word[:0]
+		}
+	}
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if len(word) == 0 {
+				start = pos
+			}
+			r = unicode.ToLower(r)
+			if r == 'ё' {
+				r = 'е'
+			}
+			word = append(word, r)
+		} else {
+			flush()
+		}
+		pos++
+	}
+	flush()
+	return tokens
+}
+
+// searchDoc - проиндексированное сообщение
+type searchDoc struct {
+	channel       string
+	user          string
+	timestamp     time.Time
+	isPost        bool
+	hasAttachment bool
+	terms         map[string]int // слово -> сколько раз встречается
+	length        int
+}
+
+// searchIndex - обратный индекс по тексту сообщений и именам вложений.
+// Живёт в памяти: строится из хранилища при запуске и обновляется при
+// отправке, редактировании и удалении. Нулевое значение готово к работе.
+type searchIndex struct {
+	mu          sync.RWMutex
+	docs        map[string]*searchDoc          // ID сообщения -> документ
+	postings    map[string]map[string]struct{} // слово -> ID сообщений
+	totalLength int
+
+	// Перестройка читает историю параллельно с живыми изменениями. Пока
+	// она идёт, изменённые и удалённые сообщения и удалённые каналы
+	// запоминаются, чтобы прочитанная раньше страница истории не вернула
+	// удалённое сообщение или прежний текст.
+	rebuilding      bool
+	touched         map[string]struct{}
+	removedChannels map[string]struct{}
+}
+
+// newSearchDoc разбирает сообщение в документ индекса
+func newSearchDoc(msg Message) *searchDoc {
+	text := msg.Text
+	for _, attachment := range msg.Attachments {
+		text += " " + attachment.Name
+	}
+	doc := &searchDoc{
+		channel:       msg.Channel,
+		user:          msg.User,
+		timestamp:     msg.Timestamp,
+		isPost:        msg.IsPost,
+		hasAttachment: len(msg.Attachments) > 0,
+		terms:         make(map[string]int),
+	}
+	for _, token := range tokenize(text) {
+		doc.terms[token.term]++
+		doc.length++
+	}
+	return doc
+}
+
+// add индексирует новое или изменённое сообщение, заменяя прежнюю версию
+func (idx *searchIndex) add(msg Message) {
+	doc := newSearchDoc(msg)
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.touchLocked(msg.ID)
+	idx.insertLocked(msg.ID, doc)
+}
+
+// restore индексирует сообщение, прочитанное при перестройке. Сообщения,
+// которые с начала перестройки менялись или удалялись, не трогает.
+func (idx *searchIndex) restore(msg Message) {
+	doc := newSearchDoc(msg)
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if _, changed := idx.touched[msg.ID]; changed {
+		return
+	}
+	if _, removed := idx.removedChannels[rootChannel(msg.Channel)]; removed {
+		return
+	}
+	idx.insertLocked(msg.ID, doc)
+}
+
+// beginRebuild и endRebuild ограничивают перестройку индекса
+func (idx *searchIndex) beginRebuild() {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.rebuilding = true
+	idx.touched = make(map[string]struct{})
+	idx.removedChannels = make(map[string]struct{})
+}
+
+func (idx *searchIndex) endRebuild() {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.rebuilding = false
+	idx.touched = nil
+	idx.removedChannels = nil
+}
+
+// touchLocked запоминает живое изменение сообщения на время перестройки.
+// Вызывается под idx.mu.
+func (idx *searchIndex) touchLocked(messageID string) {
+	if idx.rebuilding {
+		idx.touched[messageID] = struct{}{}
+	}
+}
+
+// insertLocked вызывается под idx.mu
+func (idx *searchIndex) insertLocked(messageID string, doc *searchDoc) {
+	if idx.docs == nil {
+		idx.docs = make(map[string]*searchDoc)
+		idx.postings = make(map[string]map[string]struct{})
+	}
+	idx.removeLocked(messageID)
+	idx.docs[messageID] = doc
+	idx.totalLength += doc.length
+	for term := range doc.terms {
+		if idx.postings[term] == nil {
+			idx.postings[term] = make(map[string]struct{})
+		}
+		idx.postings[term][messageID] = struct{}{}
+	}
+}
+
+func (idx *searchIndex) remove(messageID string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.touchLocked(messageID)
+	idx.removeLocked(messageID)
+}
+
+// removeChannel убирает из индекса сообщения канала и его тредов
+func (idx *searchIndex) removeChannel(channel string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if idx.rebuilding {
+		idx.removedChannels[channel] = struct{}{}
+	}
+	for id, doc := range idx.docs {
+		if rootChannel(doc.channel) == channel {
+			idx.removeLocked(id)
+		}
+	}
+}
+
+// removeLocked вызывается под idx.mu
+func (idx *searchIndex) removeLocked(messageID string) {
+	doc, exists := idx.docs[messageID]
+	if !exists {
+		return
+	}
+	for term := range doc.terms {
+		delete(idx.postings[term], messageID)
+		if len(idx.postings[term]) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	idx.totalLength -= doc.length
+	delete(idx.docs, messageID)
+}
+
+// matchTerms возвращает слова индекса, подходящие под слово запроса:
+// само слово и слова, которые с него начинаются. Вызывается под idx.mu.
+func (idx *searchIndex) matchTerms(term string) map[string]float64 {
+	matches := map[string]float64{}
+	if _, exists := idx.postings[term]; exists {
+		matches[term] = 1
+	}
+	for indexed := range idx.postings {
+		if indexed != term && strings.HasPrefix(indexed, term) {
+			matches[indexed] = prefixMatchWeight
+		}
+	}
+	return matches
+}
+
+// searchFilter - разобранный запрос
+type searchFilter struct {
+	terms         []string
+	channel       string
+	author        string
+	after         time.Time
+	before        time.Time
+	hasAttachment bool
+	isPost        bool
+}
+
+// parseSearchDate принимает дату ГГГГ-ММ-ДД (в местном времени) или RFC3339.
+// Для after дата без времени означает "после этого дня".
+func parseSearchDate(value string, after bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation(time.DateOnly, value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("неверная дата %s, ожидается ГГГГ-ММ-ДД", value)
+	}
+	if after {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+func parseSearchQuery(query SearchQuery) (searchFilter, error) {
+	filter := searchFilter{
+		channel:       query.Channel,
+		author:        query.Author,
+		hasAttachment: query.HasAttachment,
+		isPost:        query.IsPost,
+	}
+	after, before := query.After, query.Before
+
+	words := []string{}
+	for _, field := range strings.Fields(query.Text) {
+		key, value, _ := strings.Cut(field, ":")
+		if value == "" {
+			words = append(words, field)
+			continue
+		}
+		switch strings.ToLower(key) {
+		case "in":
+			filter.channel = strings.TrimPrefix(value, "#")
+		case "from":
+			filter.author = strings.TrimPrefix(value, "@")
+		case "after":
+			after = value
+		case "before":
+			before = value
+		case "has":
+			if strings.EqualFold(value, "attachment") || strings.EqualFold(value, "file") {
+				filter.hasAttachment = true
+				continue
+			}
+			words = append(words, field)
+		case "is":
+			if strings.EqualFold(value, "post") {
+				filter.isPost = true
+				continue
+			}
+			words = append(words, field)
+		default:
+			words = append(words, field)
+		}
+	}
+
+	var err error
+	if after != "" {
+		if filter.after, err = parseSearchDate(after, true); err != nil {
+			return searchFilter{}, err
+		}
+	}
+	if before != "" {
+		if filter.before, err = parseSearchDate(before, false); err != nil {
+			return searchFilter{}, err
+		}
+	}
+
+	for _, token := range tokenize(strings.Join(words, " ")) {
+		if !contains(filter.terms, token.term) {
+			filter.terms = append(filter.terms, token.term)
+		}
+	}
+
+	if len(filter.terms) == 0 && filter.channel == "" && filter.author == "" &&
+		filter.after.IsZero() && filter.before.IsZero() && !filter.hasAttachment && !filter.isPost {
+		return searchFilter{}, fmt.Errorf("введите текст или фильтр для поиска")
+	}
+	return filter, nil
+}
+
+// matches проверяет фильтры запроса, кроме текста
+func (f searchFilter) matches(doc *searchDoc) bool {
+	switch {
+	case f.channel != "" && rootChannel(doc.channel) != f.channel:
+		return false
+	case f.author != "" && doc.user != f.author:
+		return false
+	case !f.after.IsZero() && doc.timestamp.Before(f.after):
+		return false
+	case !f.before.IsZero() && !doc.timestamp.Before(f.before):
+		return false
+	case f.hasAttachment && !doc.hasAttachment:
+		return false
+	case f.isPost && !doc.isPost:
+		return false
+	}
+	return true
+}
+
+type scoredDoc struct {
+	id    string
+	doc   *searchDoc
+	score float64
+}
+
+// query находит документы, в которых есть все слова запроса (или их начала),
+// проходят фильтры и канал которых разрешён allowed. Результат отсортирован
+// по BM25, при равенстве - от новых к старым.
+func (idx *searchIndex) query(filter searchFilter, allowed func(channel string) bool) []scoredDoc {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	if len(idx.docs) == 0 {
+		return nil
+	}
+
+	// Слово запроса вместе со всеми продолжениями считается одним термином
+	// BM25: редкость (idf) - по всем документам, где есть любое из них, а
+	// частота в документе - взвешенная сумма, где продолжение весит меньше.
+	// Иначе редкое продолжение обгоняло бы точное совпадение частого слова.
+	termMatches := make([]map[string]float64, 0, len(filter.terms))
+	termDocs := make([]int, 0, len(filter.terms))
+	for _, term := range filter.terms {
+		matches := idx.matchTerms(term)
+		if len(matches) == 0 {
+			return nil
+		}
+		docs := map[string]struct{}{}
+		for indexed := range matches {
+			for id := range idx.postings[indexed] {
+				docs[id] = struct{}{}
+			}
+		}
+		termMatches = append(termMatches, matches)
+		termDocs = append(termDocs, len(docs))
+	}
+
+	// кандидаты - документы с первым словом запроса, либо все, если слов нет
+	candidates := map[string]struct{}{}
+	if len(termMatches) > 0 {
+		for term := range termMatches[0] {
+			for id := range idx.postings[term] {
+				candidates[id] = struct{}{}
+			}
+		}
+	} else {
+		for id := range idx.docs {
+			candidates[id] = struct{}{}
+		}
+	}
+
+	total := float64(len(idx.docs))
+	avgLength := float64(idx.totalLength) / total
+	if avgLength == 0 {
+		avgLength = 1
+	}
+
+	results := []scoredDoc{}
+	for id := range candidates {
+		doc := idx.docs[id]
+		if !filter.matches(doc) || !allowed(doc.channel) {
+			continue
+		}
+
+		score, matchedAll := 0.0, true
+		for i, matches := range termMatches {
+			tf := 0.0
+			for term, weight := range matches {
+				tf += weight * float64(doc.terms[term])
+			}
+			if tf == 0 {
+				matchedAll = false
+				break
+			}
+			df := float64(termDocs[i])
+			idf := math.Log(1 + (total-df+0.5)/(df+0.5))
+			norm := tf + bm25K1*(1-bm25B+bm25B*float64(doc.length)/avgLength)
+			score += idf * tf * (bm25K1 + 1) / norm
+		}
+		if matchedAll {
+			results = append(results, scoredDoc{id: id, doc: doc, score: score})
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].score != results[j].score {
+			return results[i].score > results[j].score
+		}
+		return results[i].doc.timestamp.After(results[j].doc.timestamp)
+	})
+	return results
+}
+
+// highlightRanges возвращает позиции слов текста, совпавших со словами запроса
+func highlightRanges(text string, terms []string) []TextRange {
+	ranges := []TextRange{}
+	for _, token := range tokenize(text) {
+		for _, term := range terms {
+			if strings.HasPrefix(token.term, term) {
+				ranges = append(ranges, TextRange{Start: token.start, End: token.end})
+				break
+			}
+		}
+	}
+	return ranges
+}
+
+// SearchMessages ищет сообщения по тексту с фильтрами. Приватные каналы
+// и личные переписки ищутся только для их участников.
+func (a *App) SearchMessages(username string, query SearchQuery) (SearchPage, error) {
+	filter, err := parseSearchQuery(query)
+	if err != nil {
+		return SearchPage{}, err
+	}
+	if query.Limit <= 0 {
+		query.Limit = defaultSearchPageSize
+	}
+	if query.Limit > maxSearchPageSize {
+		query.Limit = maxSearchPageSize
+	}
+	if query.Offset < 0 {
+		query.Offset = 0
+	}
+
+	channels, err := a.store.GetAllChannels()
+	if err != nil {
+		return SearchPage{}, fmt.Errorf("не удалось получить каналы: %v", err)
+	}
+	accessible := make(map[string]bool, len(channels))
+	for _, channel := range channels {
+		accessible[channel.Name] = !channel.IsPrivate || contains(channel.Members, username)
+	}
+
+	allowed := func(channel string) bool {
+		return accessible[rootChannel(channel)]
+	}
+
+	found := a.search.query(filter, allowed)
+	page := SearchPage{Results: []SearchResult{}, Total: len(found)}
+
+	// Из хранилища читается только страница. Сообщение могло исчезнуть мимо
+	// индекса (например, его удалил другой экземпляр приложения на общем
+	// Redis): такое убирается из индекса, а страница добирается следующими.
+	// Следующий запрос его уже не найдёт, поэтому NextOffset сдвигается назад.
+	next, removed := query.Offset, 0
+	for ; next < len(found) && len(page.Results) < query.Limit; next++ {
+		hit := found[next]
+		msg, err := a.store.GetMessage(hit.doc.channel, hit.id)
+		if err == ErrNotFound {
+			a.search.remove(hit.id)
+			removed++
+			continue
+		}
+		if err != nil {
+			return SearchPage{}, fmt.Errorf("не удалось получить найденное сообщение: %v", err)
+		}
+		page.Results = append(page.Results, SearchResult{
+			Message:    *msg,
+			Score:      hit.score,
+			Highlights: highlightRanges(msg.Text, filter.terms),
+		})
+	}
+	page.Total -= removed
+	if next < len(found) {
+		page.HasMore = true
+		page.NextOffset = next - removed
+	}
+	return page, nil
+}
+
+// rebuildSearchIndex индексирует все сообщения каналов и тредов из
+// хранилища. Работает в фоне одновременно с отправкой и правками: живые
+// изменения новее прочитанной истории и перестройкой не перезаписываются.
+func (a *App) rebuildSearchIndex() {
+	a.search.beginRebuild()
+	defer a.search.endRebuild()
+
+	channels, err := a.store.GetAllChannels()
+	if err != nil {
+		log.Printf("❌ Не удалось построить поисковый индекс: %v", err)
+		return
+	}
+
+	indexed := 0
+	for _, channel := range channels {
+		indexed += a.indexChannel(channel.Name)
+	}
+	log.Printf("🔍 Поисковый индекс построен: %d сообщений", indexed)
+}
+
+// indexChannel индексирует историю канала постранично от новых к старым,
+// заодно обходя треды корневых сообщений
+func (a *App) indexChannel(channel string) int {
+	indexed := 0
+	query := HistoryQuery{Limit: maxHistoryPageSize}
+	for {
+		page, err := a.store.GetMessageHistory(channel, query)
+		if err != nil {
+			log.Printf("Ошибка индексации #%s: %v", channel, err)
+			return indexed
+		}
+		for _, msg := range page.Messages {
+			a.search.restore(msg)
+			indexed++
+			if msg.ReplyCount > 0 {
+				indexed += a.indexChannel(threadChannel(channel, msg.ID))
+			}
+		}
+		if !page.HasMore || page.NextCursor == "" {
+			return indexed
+		}
+		query.Before = page.NextCursor
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTokenize(t *testing.T) {
+	tokens := tokenize("Ёлка, DEPLOY-2!")
+	want := []searchToken{
+		{term: "елка", start: 0, end: 4},
+		{term: "deploy", start: 6, end: 12},
+		{term: "2", start: 13, end: 14},
+	}
+	if !reflect.DeepEqual(tokens, want) {
+		t.Errorf("tokenize = %+v, ожидалось %+v", tokens, want)
+	}
+}
+
+func TestParseSearchQuery(t *testing.T) {
+	day := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		name    string
+		query   SearchQuery
+		want    searchFilter
+		wantErr bool
+	}{
+		{
+			name:  "слова",
+			query: SearchQuery{Text: "Деплой деплой сервера"},
+			want:  searchFilter{terms: []string{"деплой", "сервера"}},
+		},
+		{
+			name:  "операторы",
+			query: SearchQuery{Text: "релиз in:#dev-team from:@bob has:attachment is:post"},
+			want:  searchFilter{terms: []string{"релиз"}, channel: "dev-team", author: "bob", hasAttachment: true, isPost: true},
+		},
+		{
+			name:  "даты",
+			query: SearchQuery{Text: "after:2026-01-10 before:2026-02-01"},
+			want:  searchFilter{terms: nil, after: day(2026, 1, 11), before: day(2026, 2, 1)},
+		},
+		{
+			name:  "точное время",
+			query: SearchQuery{After: "2026-01-10T15:04:05Z"},
+			want:  searchFilter{after: time.Date(2026, 1, 10, 15, 4, 5, 0, time.UTC)},
+		},
+		{
+			name:  "поля запроса",
+			query: SearchQuery{Channel: "general", Author: "al", IsPost: true},
+			want:  searchFilter{channel: "general", author: "al", isPost: true},
+		},
+		{
+			name:  "незнакомые операторы - это слова",
+			query: SearchQuery{Text: "is:draft in: http:x"},
+			want:  searchFilter{terms: []string{"is", "draft", "in", "http", "x"}},
+		},
+		{name: "неверная дата", query: SearchQuery{Text: "деплой after:вчера"}, wantErr: true},
+		{name: "пустой запрос", query: SearchQuery{Text: "  ,.  "}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSearchQuery(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ошибка %v, ожидалась: %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(got.terms, tt.want.terms) && len(got.terms)+len(tt.want.terms) > 0 {
+				t.Errorf("слова %v, ожидались %v", got.terms, tt.want.terms)
+			}
+			got.terms, tt.want.terms = nil, nil
+			if !got.after.Equal(tt.want.after) || !got.before.Equal(tt.want.before) {
+				t.Errorf("даты %v - %v, ожидались %v - %v", got.after, got.before, tt.want.after, tt.want.before)
+			}
+			got.after, got.before, tt.want.after, tt.want.before = time.Time{}, time.Time{}, time.Time{}, time.Time{}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("фильтр %+v, ожидался %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchIndexQuery(t *testing.T) {
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	var idx searchIndex
+	for i, msg := range []Message{
+		{ID: "exact", Channel: "general", User: "al", Text: "деплой прошёл"},
+		{ID: "prefix", Channel: "general", User: "al", Text: "деплоймент готов"},
+		{ID: "twice", Channel: "general", User: "bob", Text: "деплой, снова деплой"},
+		{ID: "post", Channel: "random", User: "bob", Text: "деплой в пятницу", IsPost: true},
+		{ID: "reply", Channel: threadChannel("random", "post"), User: "al", Text: "деплой отменили"},
+		{ID: "file", Channel: "general", User: "al", Text: "отчёт", Attachments: []Attachment{{Name: "деплой.pdf"}}},
+		{ID: "secret", Channel: "secret", User: "al", Text: "секретный деплой"},
+		{ID: "other", Channel: "general", User: "al", Text: "обед"},
+	} {
+		msg.Timestamp = base.Add(time.Duration(i) * time.Minute)
+		idx.add(msg)
+	}
+	public := func(channel string) bool { return rootChannel(channel) != "secret" }
+
+	tests := []struct {
+		name   string
+		filter searchFilter
+		want   []string
+	}{
+		// чаще встречается - выше, короче сообщение - выше, при равном
+		// счёте выше более новое, продолжение слова - ниже точного совпадения
+		{"ранжирование", searchFilter{terms: []string{"деплой"}}, []string{"twice", "reply", "exact", "file", "post", "prefix"}},
+		{"все слова сразу", searchFilter{terms: []string{"деплой", "пятн"}}, []string{"post"}},
+		{"слово не найдено", searchFilter{terms: []string{"релиз"}}, []string{}},
+		{"канал вместе с тредами", searchFilter{terms: []string{"деплой"}, channel: "random"}, []string{"reply", "post"}},
+		{"автор", searchFilter{terms: []string{"деплой"}, author: "bob"}, []string{"twice", "post"}},
+		{"только посты", searchFilter{terms: []string{"деплой"}, isPost: true}, []string{"post"}},
+		{"с вложением", searchFilter{hasAttachment: true}, []string{"file"}},
+		{"период", searchFilter{after: base.Add(2 * time.Minute), before: base.Add(4 * time.Minute)}, []string{"post", "twice"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			for _, hit := range idx.query(tt.filter, public) {
+				got = append(got, hit.id)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("найдено %v, ожидалось %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Перестройка не должна возвращать удалённое или перезаписывать изменённое
+func TestSearchIndexRestore(t *testing.T) {
+	var idx searchIndex
+	stale := Message{ID: "m1", Channel: "general", Text: "старый текст"}
+	deleted := Message{ID: "m2", Channel: "general", Text: "удалённое"}
+	dropped := Message{ID: "m3", Channel: "gone", Text: "канал удалён"}
+
+	idx.beginRebuild()
+	idx.add(Message{ID: "m1", Channel: "general", Text: "новый текст"})
+	idx.remove("m2")
+	idx.removeChannel("gone")
+	idx.restore(stale)
+	idx.restore(deleted)
+	idx.restore(dropped)
+	idx.restore(Message{ID: "m4", Channel: "general", Text: "из истории"})
+	idx.endRebuild()
+
+	all := func(string) bool { return true }
+	for _, tt := range []struct {
+		term string
+		want int
+	}{{"старый", 0}, {"новый", 1}, {"удаленное", 0}, {"канал", 0}, {"истории", 1}} {
+		if got := len(idx.query(searchFilter{terms: []string{tt.term}}, all)); got != tt.want {
+			t.Errorf("%q: найдено %d, ожидалось %d", tt.term, got, tt.want)
+		}
+	}
+}
+
+func TestHighlightRanges(t *testing.T) {
+	got := highlightRanges("Ёлка и ёлочка, не палка", []string{"елк", "ел"})
+	want := []TextRange{{Start: 0, End: 4}, {Start: 7, End: 13}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("подсветка %v, ожидалась %v", got, want)
+	}
+}
+
+// countingStore считает чтения сообщений по ID
+type countingStore struct {
+	Store
+	gets int
+}
+
+func (s *countingStore) GetMessage(channel, messageID string) (*Message, error) {
+	s.gets++
+	return s.Store.GetMessage(channel, messageID)
+}
+
+// Из хранилища читается только страница, а сообщение, удалённое мимо
+// индекса, выпадает из выдачи без пропуска соседних
+func TestSearchMessagesPaging(t *testing.T) {
+	app := newTestApp(t, "al")
+	store := &countingStore{Store: app.store}
+	app.store = store
+
+	for i := 0; i < 30; i++ {
+		if _, err := app.SendMessage("al", fmt.Sprintf("деплой номер %d", i), "general"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	first, err := app.SearchMessages("al", SearchQuery{Text: "деплой", Limit: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.gets != 5 {
+		t.Errorf("прочитано %d сообщений для страницы из 5", store.gets)
+	}
+
+	// удаляем мимо индекса сообщение со второй страницы
+	second, err := app.SearchMessages("al", SearchQuery{Text: "деплой", Limit: 5, Offset: first.NextOffset})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := app.store.DeleteMessage("general", second.Results[2].Message.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	seen := map[string]bool{}
+	total := 0
+	query := SearchQuery{Text: "деплой", Limit: 5}
+	for pages := 0; ; pages++ {
+		if pages > 10 {
+			t.Fatal("выдача не закончилась")
+		}
+		page, err := app.SearchMessages("al", query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, result := range page.Results {
+			if seen[result.Message.ID] {
+				t.Errorf("%s выдан дважды", result.Message.ID)
+			}
+			seen[result.Message.ID] = true
+		}
+		total = page.Total
+		if !page.HasMore {
+			break
+		}
+		query.Offset = page.NextOffset
+	}
+	if len(seen) != 29 || total != 29 {
+		t.Errorf("найдено %d, Total %d, ожидалось 29", len(seen), total)
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	defaultHTTPAddr       = "127.0.0.1:8080"
+	serverShutdownTimeout = 5 * time.Second
+)
+
+// defaultAllowedOrigins - origin встроенного webview Wails на разных
+// платформах и dev-сервера `wails dev`
+var defaultAllowedOrigins = []string{
+	"wails://wails",
+	"http://wails.localhost",
+	"https://wails.localhost",
+	"http://localhost:34115",
+}
+
+// pinger реализуют хранилища, доступность которых можно проверить
+type pinger interface {
+	Ping() error
+}
+
+// httpAddr читает адрес HTTP-сервера из GOTHERMO_HTTP_ADDR
+func httpAddr() string {
+	return getEnv("GOTHERMO_HTTP_ADDR", defaultHTTPAddr)
+}
+
+// allowedOrigins читает список разрешённых origin из GOTHERMO_ALLOWED_ORIGINS
+// (через запятую, "*" отключает проверку)
+func allowedOrigins() []string {
+	value := os.Getenv("GOTHERMO_ALLOWED_ORIGINS")
+	if value == "" {
+		return defaultAllowedOrigins
+	}
+
+	origins := []string{}
+	for _, origin := range strings.Split(value, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, strings.TrimSuffix(origin, "/"))
+		}
+	}
+	return origins
+}
+
+// originChecker пропускает запросы без Origin (не браузер) и origin из
+// списка. Совпадение с Host запроса не проверяется: страница, которой через
+// DNS-rebinding подменили адрес на 127.0.0.1, пришла бы с тем же хостом.
+func originChecker(allowed []string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" || contains(allowed, "*") || contains(allowed, origin) {
+			return true
+		}
+		log.Printf("🚫 WebSocket с чужого origin отклонён: %s", origin)
+		return false
+	}
+}
+
+// newHTTPHandler собирает маршруты HTTP-сервера
+func (a *App) newHTTPHandler() http.Handler {
+	a.hub.upgrader.CheckOrigin = originChecker(allowedOrigins())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", a.hub.ServeWS)
+	mux.HandleFunc("/health", a.handleHealth)
+	mux.HandleFunc("POST /attachments", a.handleUpload)
+	mux.HandleFunc("GET /attachments/{hash}", a.handleDownload)
+	return mux
+}
+
+// handleHealth отвечает 200, если хранилище доступно, иначе 503
+func (a *App) handleHealth(w http.ResponseWriter, r *http.Request) {
+	users, connections := a.hub.ClientCount()
+	status := map[string]interface{}{
+		"status":      "ok",
+		"users":       users,
+		"connections": connections,
+	}
+	code := http.StatusOK
+	if p, ok := a.store.(pinger); ok {
+		if err := p.Ping(); err != nil {
+			status["status"] = "degraded"
+			status["error"] = err.Error()
+			code = http.StatusServiceUnavailable
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(status)
+}
+
+// startServer поднимает HTTP-сервер с /ws, /health и /attachments. Если порт
+// занят, приложение продолжает работать без real-time доставки.
+func (a *App) startServer() {
+	listener, err := net.Listen("tcp", httpAddr())
+	if err != nil {
+		log.Printf("❌ Не удалось запустить HTTP-сервер на %s: %v", httpAddr(), err)
+		return
+	}
+
+	a.server = &http.Server{
+		Handler:           a.newHTTPHandler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	log.Printf("✓ HTTP-сервер слушает %s", listener.Addr())
+
+	go func() {
+		if err := a.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Printf("❌ HTTP-сервер остановлен с ошибкой: %v", err)
+		}
+	}()
+}
+
+// stopServer перестаёт принимать соединения и закрывает уже открытые сокеты
+func (a *App) stopServer() {
+	if a.server == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+	defer cancel()
+	if err := a.server.Shutdown(ctx); err != nil {
+		log.Printf("Ошибка остановки HTTP-сервера: %v", err)
+	}
+	// Shutdown не трогает соединения, перехваченные апгрейдом
+	a.hub.CloseAll()
+}
+
+// GetWebSocketURL возвращает адрес, к которому подключается фронтенд:
+// WS_SERVER_URL, если задан, иначе адрес встроенного сервера
+func (a *App) GetWebSocketURL() string {
+	if serverURL := os.Getenv("WS_SERVER_URL"); serverURL != "" {
+		return serverURL
+	}
+
+	host, port, err := net.SplitHostPort(httpAddr())
+	if err != nil {
+		return "ws://" + defaultHTTPAddr + "/ws"
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	return "ws://" + net.JoinHostPort(host, port) + "/ws"
+}
+
+// CloseAll закрывает все WebSocket-соединения при остановке приложения
+func (h *Hub) CloseAll() {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	for _, connections := range h.clients {
+		for client := range connections {
+			closeWithCode(client.Conn, websocket.CloseGoingAway)
+		}
+	}
+}
+
+// ClientCount возвращает число подключённых пользователей и их подключений
+func (h *Hub) ClientCount() (users, connections int) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	for _, clients := range h.clients {
+		connections += len(clients)
+	}
+	return len(h.clients), connections
+}
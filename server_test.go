@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOriginChecker(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		host    string
+		origin  string
+		want    bool
+	}{
+		{"не браузер", defaultAllowedOrigins, "127.0.0.1:8080", "", true},
+		{"webview Wails", defaultAllowedOrigins, "127.0.0.1:8080", "wails://wails", true},
+		{"wails dev", defaultAllowedOrigins, "127.0.0.1:8080", "http://localhost:34115", true},
+		{"чужой сайт", defaultAllowedOrigins, "127.0.0.1:8080", "https://evil.example", false},
+		{"тот же хост не из списка", defaultAllowedOrigins, "127.0.0.1:8080", "http://127.0.0.1:8080", false},
+		{"DNS-rebinding", defaultAllowedOrigins, "evil.example:8080", "http://evil.example:8080", false},
+		{"свой список", []string{"https://chat.example"}, "chat.example", "https://chat.example", true},
+		{"проверка отключена", []string{"*"}, "127.0.0.1:8080", "https://evil.example", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "http://"+tt.host+"/ws", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if got := originChecker(tt.allowed)(r); got != tt.want {
+				t.Errorf("originChecker(%q) = %v, ожидалось %v", tt.origin, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const defaultSessionTTL = 30 * 24 * time.Hour
+
+// errSessionRevoked - токен подписан верно, но его семейство уже отозвано
+var errSessionRevoked = errors.New("сессия отозвана")
+
+// Session - запись refresh-токена. Сам токен не хранится, только его
+// SHA-256 хеш. Все токены, выданные одному входу, образуют семейство с общим
+// ID: при обновлении старая запись помечается RotatedAt и остаётся до
+// истечения, чтобы распознать повторное использование украденного токена.
+type Session struct {
+	ID        string     `json:"id"`
+	TokenHash string     `json:"tokenHash"`
+	Username  string     `json:"username"`
+	Email     string     `json:"email"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	RotatedAt *time.Time `json:"rotatedAt,omitempty"`
+}
+
+// SessionInfo - сессия в том виде, в каком её видит пользователь
+type SessionInfo struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	Current   bool      `json:"current"`
+}
+
+// TokenPair - пара токенов, выдаваемая при входе и обновлении
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+}
+
+// sessionTTL читает время жизни refresh-токена из GOTHERMO_SESSION_TTL
+// (формат time.ParseDuration, например "720h")
+func sessionTTL() time.Duration {
+	if value := os.Getenv("GOTHERMO_SESSION_TTL"); value != "" {
+		if ttl, err := time.ParseDuration(value); err == nil && ttl > 0 {
+			return ttl
+		}
+		log.Printf("Неверный GOTHERMO_SESSION_TTL=%q, используется %v", value, defaultSessionTTL)
+	}
+	return defaultSessionTTL
+}
+
+// generateToken возвращает криптографически случайный токен (256 бит)
+func generateToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueTokens сохраняет новый refresh-токен семейства familyID и выпускает
+// к нему access-токен
+func (um *UserManager) issueTokens(familyID, username, email string, createdAt time.Time) (TokenPair, error) {
+	refreshToken, err := generateToken()
+	if err != nil {
+		return TokenPair{}, fmt.Errorf("не удалось создать токен: %v", err)
+	}
+
+	session := Session{
+		ID:        familyID,
+		TokenHash: hashToken(refreshToken),
+		Username:  username,
+		Email:     email,
+		CreatedAt: createdAt,
+		ExpiresAt: time.Now().Add(sessionTTL()),
+	}
+	if err := um.store.SaveSession(session); err != nil {
+		return TokenPair{}, fmt.Errorf("не удалось сохранить сессию: %v", err)
+	}
+
+	accessToken, _, err := um.keys.sign(session)
+	if err != nil {
+		return TokenPair{}, fmt.Errorf("не удалось подписать токен: %v", err)
+	}
+	return TokenPair{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+// CreateSession начинает новое семейство токенов для пользователя
+func (um *UserManager) CreateSession(user *User) (TokenPair, error) {
+	return um.issueTokens(uuid.New().String(), user.Username, user.Email, time.Now())
+}
+
+// RefreshSession обменивает refresh-токен на новую пару. Повторное
+// предъявление уже обменянного токена означает утечку: всё семейство
+// отзывается, и владельцу придётся войти заново.
+func (um *UserManager) RefreshSession(refreshToken string) (TokenPair, error) {
+	um.refreshMu.Lock()
+	defer um.refreshMu.Unlock()
+
+	session, err := um.store.GetSession(hashToken(refreshToken))
+	if err != nil || time.Now().After(session.ExpiresAt) {
+		return TokenPair{}, fmt.Errorf("сессия недействительна")
+	}
+
+	if session.RotatedAt != nil {
+		log.Printf("⚠️ Повторное использование refresh-токена %s, сессия %s отозвана", session.Username, session.ID)
+		um.revokeFamily(session.Username, session.ID)
+		return TokenPair{}, fmt.Errorf("сессия недействительна")
+	}
+
+	rotatedAt := time.Now()
+	session.RotatedAt = &rotatedAt
+	if err := um.store.SaveSession(*session); err != nil {
+		return TokenPair{}, fmt.Errorf("не удалось обновить сессию: %v", err)
+	}
+
+	return um.issueTokens(session.ID, session.Username, session.Email, session.CreatedAt)
+}
+
+// familySessions возвращает записи семейства (включая обменянные)
+func (um *UserManager) familySessions(username, familyID string) ([]Session, error) {
+	sessions, err := um.store.GetUserSessions(username)
+	if err != nil {
+		return nil, err
+	}
+
+	family := []Session{}
+	for _, session := range sessions {
+		if session.ID == familyID {
+			family = append(family, session)
+		}
+	}
+	return family, nil
+}
+
+func (um *UserManager) revokeFamily(username, familyID string) error {
+	family, err := um.familySessions(username, familyID)
+	if err != nil {
+		return err
+	}
+	for _, session := range family {
+		if err := um.store.DeleteSession(session.TokenHash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Authenticate проверяет access-токен и то, что его семейство не отозвано
+func (um *UserManager) Authenticate(token string) (*User, *AccessClaims, error) {
+	claims, err := um.keys.parse(token)
+	if err != nil {
+		return nil, nil, fmt.Errorf("токен недействителен: %w", err)
+	}
+
+	family, err := um.familySessions(claims.Subject, claims.SessionID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("не удалось проверить сессию: %v", err)
+	}
+	active := false
+	for _, session := range family {
+		if session.RotatedAt == nil {
+			active = true
+		}
+	}
+	if !active {
+		return nil, nil, errSessionRevoked
+	}
+
+	if user, exists := um.GetUser(claims.Subject); exists {
+		return user, claims, nil
+	}
+	// Пользователь мог появиться в хранилище после загрузки
+	user, err := um.store.GetUser(claims.Email)
+	if err != nil {
+		return nil, nil, fmt.Errorf("пользователь не найден")
+	}
+	return user, claims, nil
+}
+
+func (um *UserManager) GetUserByToken(token string) (*User, bool) {
+	user, _, err := um.Authenticate(token)
+	return user, err == nil
+}
+
+// RemoveUserToken отзывает семейство, выдавшее access-токен. Срок действия
+// не проверяется, чтобы выйти можно было и с просроченным токеном.
+func (um *UserManager) RemoveUserToken(token string) {
+	claims, err := um.keys.parse(token, jwt.WithoutClaimsValidation())
+	if err != nil {
+		return
+	}
+	if err := um.revokeFamily(claims.Subject, claims.SessionID); err != nil {
+		log.Printf("Ошибка удаления сессии: %v", err)
+	}
+}
+
+// withTokens возвращает копию пользователя с выданными токенами
+func withTokens(user *User, tokens TokenPair) User {
+	result := *user
+	result.Token = tokens.AccessToken
+	result.RefreshToken = tokens.RefreshToken
+	return result
+}
+
+// RefreshSession обменивает refresh-токен на новую пару токенов
+func (a *App) RefreshSession(refreshToken string) (User, error) {
+	tokens, err := userManager.RefreshSession(refreshToken)
+	if err != nil {
+		return User{}, err
+	}
+
+	user, _, err := userManager.Authenticate(tokens.AccessToken)
+	if err != nil {
+		return User{}, err
+	}
+	a.notifications.setLocalUser(user.Username)
+	return withTokens(user, tokens), nil
+}
+
+// GetSessions возвращает активные сессии владельца токена
+func (a *App) GetSessions(token string) ([]SessionInfo, error) {
+	_, claims, err := userManager.Authenticate(token)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := a.store.GetUserSessions(claims.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить сессии: %v", err)
+	}
+
+	now := time.Now()
+	infos := []SessionInfo{}
+	for _, session := range sessions {
+		if session.RotatedAt != nil || now.After(session.ExpiresAt) {
+			continue
+		}
+		infos = append(infos, SessionInfo{
+			ID:        session.ID,
+			CreatedAt: session.CreatedAt,
+			ExpiresAt: session.ExpiresAt,
+			Current:   session.ID == claims.SessionID,
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].CreatedAt.After(infos[j].CreatedAt)
+	})
+	return infos, nil
+}
+
+// RevokeSession завершает одну из сессий владельца токена
+func (a *App) RevokeSession(token, sessionID string) error {
+	_, claims, err := userManager.Authenticate(token)
+	if err != nil {
+		return err
+	}
+
+	family, err := userManager.familySessions(claims.Subject, sessionID)
+	if err != nil {
+		return fmt.Errorf("не удалось получить сессии: %v", err)
+	}
+	if len(family) == 0 {
+		return fmt.Errorf("сессия не найдена")
+	}
+
+	log.Printf("🔒 %s завершил сессию %s", claims.Subject, sessionID)
+	return userManager.revokeFamily(claims.Subject, sessionID)
+}
@@ -0,0 +1,88 @@
+package main
+
+import "testing"
+
+func TestLoginSessions(t *testing.T) {
+	app := newTestApp(t)
+	registered, err := app.Register("al@example.com", "secret123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if registered.Token == "" || registered.RefreshToken == "" {
+		t.Fatal("при регистрации не выданы токены")
+	}
+	laptop, err := app.Login("al@example.com", "secret123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.Login("al@example.com", "wrong-password"); err == nil {
+		t.Error("вход с неверным паролем")
+	}
+
+	if user, _ := app.CheckAuth(laptop.Token); user == "" {
+		t.Error("токен входа не проходит CheckAuth")
+	}
+	if user, _ := app.CheckAuth("not-a-token"); user != "" {
+		t.Error("поддельный токен прошёл CheckAuth")
+	}
+
+	sessions, err := app.GetSessions(laptop.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("сессий %d, ожидалось 2", len(sessions))
+	}
+	var other string
+	for _, session := range sessions {
+		if !session.Current {
+			other = session.ID
+		}
+	}
+
+	if err := app.RevokeSession(laptop.Token, other); err != nil {
+		t.Fatal(err)
+	}
+	if user, _ := app.CheckAuth(registered.Token); user != "" {
+		t.Error("токен отозванной сессии проходит CheckAuth")
+	}
+
+	app.Logout(laptop.Token)
+	if user, _ := app.CheckAuth(laptop.Token); user != "" {
+		t.Error("токен проходит CheckAuth после выхода")
+	}
+	if _, err := app.RefreshSession(laptop.RefreshToken); err == nil {
+		t.Error("refresh-токен работает после выхода")
+	}
+}
+
+// Обменянный refresh-токен, предъявленный повторно, отзывает всё семейство
+func TestRefreshTokenReuse(t *testing.T) {
+	app := newTestApp(t)
+	user, err := app.Register("al@example.com", "secret123")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, err := app.RefreshSession(user.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated.RefreshToken == user.RefreshToken {
+		t.Fatal("refresh-токен не сменился")
+	}
+	if name, _ := app.CheckAuth(rotated.Token); name == "" {
+		t.Fatal("новый access-токен не проходит CheckAuth")
+	}
+
+	// украденный старый токен предъявлен после обмена
+	if _, err := app.RefreshSession(user.RefreshToken); err == nil {
+		t.Fatal("обменянный refresh-токен принят повторно")
+	}
+	if _, err := app.RefreshSession(rotated.RefreshToken); err == nil {
+		t.Error("после повторного использования семейство не отозвано")
+	}
+	if name, _ := app.CheckAuth(rotated.Token); name != "" {
+		t.Error("access-токен отозванного семейства проходит CheckAuth")
+	}
+}
@@ -0,0 +1,749 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLiteStore - реализация Store во встроенной базе SQLite для установок
+// на одной машине без отдельного сервера Redis
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS channels (
+	name TEXT PRIMARY KEY,
+	data TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS invitations (
+	channel TEXT NOT NULL,
+	invitee TEXT NOT NULL,
+	data    TEXT NOT NULL,
+	PRIMARY KEY (invitee, channel)
+);
+
+CREATE TABLE IF NOT EXISTS messages (
+	seq     INTEGER PRIMARY KEY AUTOINCREMENT,
+	id      TEXT NOT NULL UNIQUE,
+	channel TEXT NOT NULL,
+	ts      INTEGER NOT NULL DEFAULT 0,
+	data    TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_messages_channel ON messages (channel, seq);
+
+CREATE TABLE IF NOT EXISTS thread_followers (
+	parent_id TEXT NOT NULL,
+	username  TEXT NOT NULL,
+	PRIMARY KEY (parent_id, username)
+);
+
+CREATE TABLE IF NOT EXISTS read_markers (
+	username TEXT NOT NULL,
+	channel  TEXT NOT NULL,
+	data     TEXT NOT NULL,
+	PRIMARY KEY (username, channel)
+);
+
+CREATE TABLE IF NOT EXISTS pins (
+	channel TEXT PRIMARY KEY,
+	data    TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS preferences (
+	username TEXT PRIMARY KEY,
+	data     TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS sessions (
+	token_hash TEXT PRIMARY KEY,
+	username   TEXT NOT NULL,
+	expires_at INTEGER NOT NULL,
+	data       TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_username ON sessions (username);
+
+CREATE TABLE IF NOT EXISTS users (
+	email TEXT PRIMARY KEY,
+	data  TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS passwords (
+	email TEXT PRIMARY KEY,
+	hash  TEXT NOT NULL
+);
+`
+
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite3", path+"?_journal_mode=WAL&_busy_timeout=5000&_foreign_keys=on")
+	if err != nil {
+		return nil, err
+	}
+	// SQLite допускает только одного писателя, сериализуем доступ на уровне пула
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("не удалось создать схему SQLite: %v", err)
+	}
+
+	store := &SQLiteStore{db: db}
+	if err := store.migrate(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("не удалось обновить схему SQLite: %v", err)
+	}
+
+	log.Printf("✓ Открыта база SQLite: %s", path)
+	return store, nil
+}
+
+func (s *SQLiteStore) Ping() error {
+	return s.db.Ping()
+}
+
+// migrate доводит схему баз, созданных старыми версиями, до текущей
+func (s *SQLiteStore) migrate() error {
+	added, err := s.addColumnIfMissing("messages", "ts", "INTEGER NOT NULL DEFAULT 0")
+	if err != nil {
+		return err
+	}
+	if added {
+		if err := s.backfillMessageTimestamps(); err != nil {
+			return err
+		}
+	}
+
+	_, err = s.db.Exec(`CREATE INDEX IF NOT EXISTS idx_messages_ts ON messages (channel, ts)`)
+	return err
+}
+
+func (s *SQLiteStore) addColumnIfMissing(table, column, definition string) (bool, error) {
+	rows, err := s.db.Query(fmt.Sprintf(`PRAGMA table_info(%s)`, table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return false, nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+	rows.Close()
+
+	_, err = s.db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, definition))
+	return err == nil, err
+}
+
+// backfillMessageTimestamps заполняет колонку ts из JSON уже сохранённых сообщений
+func (s *SQLiteStore) backfillMessageTimestamps() error {
+	rows, err := s.db.Query(`SELECT seq, data FROM messages`)
+	if err != nil {
+		return err
+	}
+
+	timestamps := make(map[int64]int64)
+	for rows.Next() {
+		var (
+			seq  int64
+			data string
+		)
+		if err := rows.Scan(&seq, &data); err != nil {
+			rows.Close()
+			return err
+		}
+
+		var msg Message
+		if err := json.Unmarshal([]byte(data), &msg); err == nil {
+			timestamps[seq] = msg.Timestamp.UnixNano()
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for seq, ts := range timestamps {
+		if _, err := s.db.Exec(`UPDATE messages SET ts = ? WHERE seq = ?`, ts, seq); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("✓ Заполнены метки времени для %d сообщений SQLite", len(timestamps))
+	return nil
+}
+
+// noRows приводит sql.ErrNoRows к ErrNotFound
+func noRows(err error) error {
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *SQLiteStore) SaveChannel(channel Channel) error {
+	data, err := json.Marshal(channel)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`INSERT INTO channels (name, data) VALUES (?, ?)
+		ON CONFLICT(name) DO UPDATE SET data = excluded.data`, channel.Name, string(data))
+	return err
+}
+
+func (s *SQLiteStore) GetChannel(name string) (*Channel, error) {
+	var data string
+	err := s.db.QueryRow(`SELECT data FROM channels WHERE name = ?`, name).Scan(&data)
+	if err != nil {
+		return nil, noRows(err)
+	}
+
+	var channel Channel
+	if err := json.Unmarshal([]byte(data), &channel); err != nil {
+		return nil, err
+	}
+
+	return &channel, nil
+}
+
+func (s *SQLiteStore) GetAllChannels() ([]Channel, error) {
+	rows, err := s.db.Query(`SELECT data FROM channels`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	channels := []Channel{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+
+		var channel Channel
+		if err := json.Unmarshal([]byte(data), &channel); err == nil {
+			channels = append(channels, channel)
+		}
+	}
+
+	return channels, rows.Err()
+}
+
+func (s *SQLiteStore) DeleteChannel(name string) error {
+	_, err := s.db.Exec(`DELETE FROM channels WHERE name = ?`, name)
+	return err
+}
+
+func (s *SQLiteStore) SaveInvitation(invitation Invitation) error {
+	data, err := json.Marshal(invitation)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`INSERT INTO invitations (channel, invitee, data) VALUES (?, ?, ?)
+		ON CONFLICT(invitee, channel) DO UPDATE SET data = excluded.data`,
+		invitation.Channel, invitation.Invitee, string(data))
+	return err
+}
+
+func (s *SQLiteStore) GetInvitations(username string) ([]Invitation, error) {
+	rows, err := s.db.Query(`SELECT data FROM invitations WHERE invitee = ?`, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []Invitation{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+
+		var invitation Invitation
+		if err := json.Unmarshal([]byte(data), &invitation); err == nil {
+			invitations = append(invitations, invitation)
+		}
+	}
+	return invitations, rows.Err()
+}
+
+func (s *SQLiteStore) DeleteInvitation(channel, username string) error {
+	_, err := s.db.Exec(`DELETE FROM invitations WHERE channel = ? AND invitee = ?`, channel, username)
+	return err
+}
+
+func (s *SQLiteStore) SaveMessage(msg Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`INSERT INTO messages (id, channel, ts, data) VALUES (?, ?, ?, ?)`,
+		msg.ID, msg.Channel, msg.Timestamp.UnixNano(), string(data))
+	return err
+}
+
+func (s *SQLiteStore) GetMessage(channel, messageID string) (*Message, error) {
+	var data string
+	err := s.db.QueryRow(`SELECT data FROM messages WHERE id = ? AND channel = ?`,
+		messageID, channel).Scan(&data)
+	if err != nil {
+		return nil, noRows(err)
+	}
+
+	var msg Message
+	if err := json.Unmarshal([]byte(data), &msg); err != nil {
+		return nil, err
+	}
+
+	return &msg, nil
+}
+
+func (s *SQLiteStore) GetMessages(channel string, limit int64) ([]Message, error) {
+	rows, err := s.db.Query(`SELECT data FROM (
+			SELECT seq, data FROM messages WHERE channel = ? ORDER BY seq DESC LIMIT ?
+		) ORDER BY seq ASC`, channel, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanMessages(rows)
+}
+
+func (s *SQLiteStore) GetMessageHistory(channel string, query HistoryQuery) (MessagePage, error) {
+	where := "channel = ?"
+	args := []interface{}{channel}
+	order := "DESC"
+
+	if cursor := query.Before + query.After; cursor != "" {
+		op := "<"
+		if query.After != "" {
+			op = ">"
+			order = "ASC"
+		}
+
+		messageID, at, isTime := parseCursor(cursor)
+		if isTime {
+			where += " AND ts " + op + " ?"
+			args = append(args, at.UnixNano())
+		} else {
+			var seq int64
+			err := s.db.QueryRow(`SELECT seq FROM messages WHERE id = ? AND channel = ?`,
+				messageID, channel).Scan(&seq)
+			if err != nil {
+				return MessagePage{}, noRows(err)
+			}
+			where += " AND seq " + op + " ?"
+			args = append(args, seq)
+		}
+	}
+
+	// Запрашиваем на одно сообщение больше, чтобы узнать, есть ли продолжение
+	args = append(args, query.Limit+1)
+	rows, err := s.db.Query(`SELECT data FROM messages WHERE `+where+
+		` ORDER BY seq `+order+` LIMIT ?`, args...)
+	if err != nil {
+		return MessagePage{}, err
+	}
+	defer rows.Close()
+
+	messages, err := scanMessages(rows)
+	if err != nil {
+		return MessagePage{}, err
+	}
+
+	page := MessagePage{Messages: messages}
+	if int64(len(messages)) > query.Limit {
+		page.HasMore = true
+		page.Messages = messages[:query.Limit]
+	}
+
+	if order == "DESC" {
+		for i, j := 0, len(page.Messages)-1; i < j; i, j = i+1, j-1 {
+			page.Messages[i], page.Messages[j] = page.Messages[j], page.Messages[i]
+		}
+	}
+
+	if len(page.Messages) > 0 {
+		if query.After != "" {
+			page.NextCursor = page.Messages[len(page.Messages)-1].ID
+		} else {
+			page.NextCursor = page.Messages[0].ID
+		}
+	}
+
+	return page, nil
+}
+
+func (s *SQLiteStore) UpdateMessage(channel string, updatedMsg Message) error {
+	data, err := json.Marshal(updatedMsg)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.db.Exec(`UPDATE messages SET data = ? WHERE id = ? AND channel = ?`,
+		string(data), updatedMsg.ID, channel)
+	if err != nil {
+		return err
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return fmt.Errorf("сообщение с ID %s не найдено", updatedMsg.ID)
+	}
+	return nil
+}
+
+// ModifyMessage выполняет чтение и запись сообщения в одной транзакции.
+// SQLite сериализует писателей, поэтому одновременные изменения не теряются.
+func (s *SQLiteStore) ModifyMessage(channel, messageID string, modify func(msg *Message) error) (*Message, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var data string
+	err = tx.QueryRow(`SELECT data FROM messages WHERE id = ? AND channel = ?`,
+		messageID, channel).Scan(&data)
+	if err != nil {
+		return nil, noRows(err)
+	}
+
+	var msg Message
+	if err := json.Unmarshal([]byte(data), &msg); err != nil {
+		return nil, err
+	}
+	if err := modify(&msg); err != nil {
+		return nil, err
+	}
+
+	updatedData, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`UPDATE messages SET data = ? WHERE id = ?`, string(updatedData), messageID); err != nil {
+		return nil, err
+	}
+
+	return &msg, tx.Commit()
+}
+
+func (s *SQLiteStore) DeleteMessage(channel, messageID string) error {
+	result, err := s.db.Exec(`DELETE FROM messages WHERE id = ? AND channel = ?`, messageID, channel)
+	if err != nil {
+		return err
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// CompactMessages ничего не делает: строки удаляются сразу, без надгробий
+func (s *SQLiteStore) CompactMessages(channel string) (int64, error) {
+	return 0, nil
+}
+
+func (s *SQLiteStore) PendingCompaction() ([]string, error) {
+	return nil, nil
+}
+
+func (s *SQLiteStore) ToggleReaction(channel, messageID, emoji, username string) (map[string][]string, error) {
+	msg, err := s.ModifyMessage(channel, messageID, func(msg *Message) error {
+		msg.Reactions = toggleReaction(msg.Reactions, emoji, username)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return msg.Reactions, nil
+}
+
+func (s *SQLiteStore) AddThreadFollower(parentID, username string) error {
+	_, err := s.db.Exec(`INSERT OR IGNORE INTO thread_followers (parent_id, username) VALUES (?, ?)`,
+		parentID, username)
+	return err
+}
+
+func (s *SQLiteStore) RemoveThreadFollower(parentID, username string) error {
+	_, err := s.db.Exec(`DELETE FROM thread_followers WHERE parent_id = ? AND username = ?`,
+		parentID, username)
+	return err
+}
+
+func (s *SQLiteStore) GetThreadFollowers(parentID string) ([]string, error) {
+	rows, err := s.db.Query(`SELECT username FROM thread_followers WHERE parent_id = ?`, parentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	followers := []string{}
+	for rows.Next() {
+		var username string
+		if err := rows.Scan(&username); err != nil {
+			return nil, err
+		}
+		followers = append(followers, username)
+	}
+	return followers, rows.Err()
+}
+
+func (s *SQLiteStore) SaveReadMarker(marker ReadMarker) error {
+	data, err := json.Marshal(marker)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`INSERT INTO read_markers (username, channel, data) VALUES (?, ?, ?)
+		ON CONFLICT(username, channel) DO UPDATE SET data = excluded.data`,
+		marker.Username, marker.Channel, string(data))
+	return err
+}
+
+func (s *SQLiteStore) GetReadMarkers(username string) (map[string]ReadMarker, error) {
+	rows, err := s.db.Query(`SELECT channel, data FROM read_markers WHERE username = ?`, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	markers := make(map[string]ReadMarker)
+	for rows.Next() {
+		var channel, data string
+		if err := rows.Scan(&channel, &data); err != nil {
+			return nil, err
+		}
+
+		var marker ReadMarker
+		if err := json.Unmarshal([]byte(data), &marker); err == nil {
+			markers[channel] = marker
+		}
+	}
+	return markers, rows.Err()
+}
+
+func (s *SQLiteStore) SavePins(channel string, pins []Pin) error {
+	if len(pins) == 0 {
+		_, err := s.db.Exec(`DELETE FROM pins WHERE channel = ?`, channel)
+		return err
+	}
+
+	data, err := json.Marshal(pins)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`INSERT INTO pins (channel, data) VALUES (?, ?)
+		ON CONFLICT(channel) DO UPDATE SET data = excluded.data`,
+		channel, string(data))
+	return err
+}
+
+func (s *SQLiteStore) GetPins(channel string) ([]Pin, error) {
+	var data string
+	err := s.db.QueryRow(`SELECT data FROM pins WHERE channel = ?`, channel).Scan(&data)
+	if err == sql.ErrNoRows {
+		return []Pin{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	pins := []Pin{}
+	if err := json.Unmarshal([]byte(data), &pins); err != nil {
+		return nil, err
+	}
+	return pins, nil
+}
+
+func (s *SQLiteStore) SavePreferences(prefs UserPreferences) error {
+	data, err := json.Marshal(prefs)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`INSERT INTO preferences (username, data) VALUES (?, ?)
+		ON CONFLICT(username) DO UPDATE SET data = excluded.data`,
+		prefs.Username, string(data))
+	return err
+}
+
+func (s *SQLiteStore) GetPreferences(username string) (*UserPreferences, error) {
+	var data string
+	err := s.db.QueryRow(`SELECT data FROM preferences WHERE username = ?`, username).Scan(&data)
+	if err != nil {
+		return nil, noRows(err)
+	}
+
+	var prefs UserPreferences
+	if err := json.Unmarshal([]byte(data), &prefs); err != nil {
+		return nil, err
+	}
+	return &prefs, nil
+}
+
+func (s *SQLiteStore) SaveSession(session Session) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`INSERT INTO sessions (token_hash, username, expires_at, data) VALUES (?, ?, ?, ?)
+		ON CONFLICT(token_hash) DO UPDATE SET expires_at = excluded.expires_at, data = excluded.data`,
+		session.TokenHash, session.Username, session.ExpiresAt.UnixNano(), string(data))
+	return err
+}
+
+func (s *SQLiteStore) GetSession(tokenHash string) (*Session, error) {
+	var data string
+	err := s.db.QueryRow(`SELECT data FROM sessions WHERE token_hash = ? AND expires_at > ?`,
+		tokenHash, time.Now().UnixNano()).Scan(&data)
+	if err != nil {
+		return nil, noRows(err)
+	}
+
+	var session Session
+	if err := json.Unmarshal([]byte(data), &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (s *SQLiteStore) DeleteSession(tokenHash string) error {
+	_, err := s.db.Exec(`DELETE FROM sessions WHERE token_hash = ?`, tokenHash)
+	return err
+}
+
+// GetUserSessions возвращает живые сессии пользователя, предварительно
+// удаляя все истёкшие
+func (s *SQLiteStore) GetUserSessions(username string) ([]Session, error) {
+	now := time.Now().UnixNano()
+	if _, err := s.db.Exec(`DELETE FROM sessions WHERE expires_at <= ?`, now); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`SELECT data FROM sessions WHERE username = ?`, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []Session{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+
+		var session Session
+		if err := json.Unmarshal([]byte(data), &session); err == nil {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, rows.Err()
+}
+
+func (s *SQLiteStore) SaveUser(user *User) error {
+	data, err := json.Marshal(user)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`INSERT INTO users (email, data) VALUES (?, ?)
+		ON CONFLICT(email) DO UPDATE SET data = excluded.data`, user.Email, string(data))
+	return err
+}
+
+func (s *SQLiteStore) GetUser(email string) (*User, error) {
+	var data string
+	err := s.db.QueryRow(`SELECT data FROM users WHERE email = ?`, email).Scan(&data)
+	if err != nil {
+		return nil, noRows(err)
+	}
+
+	var user User
+	if err := json.Unmarshal([]byte(data), &user); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (s *SQLiteStore) GetAllUsers() ([]User, error) {
+	rows, err := s.db.Query(`SELECT data FROM users`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+
+		var user User
+		if err := json.Unmarshal([]byte(data), &user); err == nil {
+			users = append(users, user)
+		}
+	}
+
+	log.Printf("📊 Загружено %d пользователей из SQLite", len(users))
+	return users, rows.Err()
+}
+
+func (s *SQLiteStore) SaveUserPassword(email, hashedPassword string) error {
+	_, err := s.db.Exec(`INSERT INTO passwords (email, hash) VALUES (?, ?)
+		ON CONFLICT(email) DO UPDATE SET hash = excluded.hash`, email, hashedPassword)
+	return err
+}
+
+func (s *SQLiteStore) GetUserPassword(email string) (string, error) {
+	var hash string
+	err := s.db.QueryRow(`SELECT hash FROM passwords WHERE email = ?`, email).Scan(&hash)
+	return hash, noRows(err)
+}
+
+// scanMessages декодирует JSON сообщений из выборки, пропуская битые записи
+func scanMessages(rows *sql.Rows) ([]Message, error) {
+	messages := []Message{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+
+		var msg Message
+		if err := json.Unmarshal([]byte(data), &msg); err == nil {
+			messages = append(messages, msg)
+		}
+	}
+
+	return messages, rows.Err()
+}
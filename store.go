@@ -0,0 +1,217 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ErrNotFound возвращается хранилищем, когда запись отсутствует
+var ErrNotFound = errors.New("не найдено")
+
+// parseCursor разбирает курсор истории: время в RFC3339 или ID сообщения
+func parseCursor(cursor string) (messageID string, at time.Time, isTime bool) {
+	if t, err := time.Parse(time.RFC3339Nano, cursor); err == nil {
+		return "", t, true
+	}
+	return cursor, time.Time{}, false
+}
+
+// historyRange вычисляет включительный диапазон позиций страницы в списке
+// длины length. cursorPos - позиция первого сообщения, которое уже не входит
+// в страницу при Before, либо первого подходящего сообщения при After.
+func historyRange(query HistoryQuery, length, cursorPos int64) (start, end int64) {
+	switch {
+	case query.Before != "":
+		end = cursorPos - 1
+		start = end - query.Limit + 1
+	case query.After != "":
+		start = cursorPos
+		end = start + query.Limit - 1
+	default:
+		end = length - 1
+		start = length - query.Limit
+	}
+
+	if start < 0 {
+		start = 0
+	}
+	if end > length-1 {
+		end = length - 1
+	}
+	return start, end
+}
+
+// newMessagePage собирает страницу по сообщениям из диапазона [start, end]
+func newMessagePage(messages []Message, query HistoryQuery, start, end, length int64) MessagePage {
+	page := MessagePage{Messages: messages}
+	if query.After != "" {
+		page.HasMore = end < length-1
+	} else {
+		page.HasMore = start > 0
+	}
+
+	if len(messages) > 0 {
+		if query.After != "" {
+			page.NextCursor = messages[len(messages)-1].ID
+		} else {
+			page.NextCursor = messages[0].ID
+		}
+	}
+	return page
+}
+
+// toggleReaction снимает реакцию, если пользователь уже её поставил, иначе
+// добавляет. Пустые списки удаляются из карты.
+func toggleReaction(reactions map[string][]string, emoji, username string) map[string][]string {
+	if reactions == nil {
+		reactions = make(map[string][]string)
+	}
+
+	users := reactions[emoji]
+	newUsers := []string{}
+	alreadyReacted := false
+	for _, u := range users {
+		if u == username {
+			alreadyReacted = true
+			continue
+		}
+		newUsers = append(newUsers, u)
+	}
+	if !alreadyReacted {
+		newUsers = append(newUsers, username)
+	}
+
+	if len(newUsers) == 0 {
+		delete(reactions, emoji)
+	} else {
+		reactions[emoji] = newUsers
+	}
+	return reactions
+}
+
+// ChannelStore хранит каналы
+type ChannelStore interface {
+	SaveChannel(channel Channel) error
+	GetChannel(name string) (*Channel, error)
+	GetAllChannels() ([]Channel, error)
+	DeleteChannel(name string) error
+}
+
+// InvitationStore хранит приглашения в приватные каналы
+type InvitationStore interface {
+	SaveInvitation(invitation Invitation) error
+	GetInvitations(username string) ([]Invitation, error)
+	DeleteInvitation(channel, username string) error
+}
+
+// MessageStore хранит историю сообщений каналов
+type MessageStore interface {
+	SaveMessage(msg Message) error
+	GetMessage(channel, messageID string) (*Message, error)
+	GetMessages(channel string, limit int64) ([]Message, error)
+	GetMessageHistory(channel string, query HistoryQuery) (MessagePage, error)
+	UpdateMessage(channel string, msg Message) error
+	// ModifyMessage атомарно применяет modify к сохранённому сообщению.
+	// Ошибка из modify отменяет изменение и возвращается как есть.
+	ModifyMessage(channel, messageID string, modify func(msg *Message) error) (*Message, error)
+	DeleteMessage(channel, messageID string) error
+	// CompactMessages физически удаляет надгробия удалённых сообщений канала
+	// и возвращает число убранных записей
+	CompactMessages(channel string) (int64, error)
+	// PendingCompaction возвращает каналы (включая треды), в которых есть надгробия
+	PendingCompaction() ([]string, error)
+	// ToggleReaction атомарно ставит или снимает реакцию пользователя
+	// и возвращает итоговые реакции сообщения
+	ToggleReaction(channel, messageID, emoji, username string) (map[string][]string, error)
+}
+
+// ThreadStore хранит подписчиков тредов (ключ - ID корневого сообщения)
+type ThreadStore interface {
+	AddThreadFollower(parentID, username string) error
+	RemoveThreadFollower(parentID, username string) error
+	GetThreadFollowers(parentID string) ([]string, error)
+}
+
+// ReadMarkerStore хранит отметки прочтения (пользователь -> канал -> отметка)
+type ReadMarkerStore interface {
+	SaveReadMarker(marker ReadMarker) error
+	GetReadMarkers(username string) (map[string]ReadMarker, error)
+}
+
+// PinStore хранит закреплённые сообщения канала одним упорядоченным списком.
+// Пустой список удаляет запись, GetPins для канала без закреплений
+// возвращает пустой список.
+type PinStore interface {
+	SavePins(channel string, pins []Pin) error
+	GetPins(channel string) ([]Pin, error)
+}
+
+// PreferenceStore хранит настройки пользователей (ключ - имя пользователя).
+// GetPreferences возвращает ErrNotFound, если пользователь ничего не менял.
+type PreferenceStore interface {
+	SavePreferences(prefs UserPreferences) error
+	GetPreferences(username string) (*UserPreferences, error)
+}
+
+// SessionStore хранит сессии входа (ключ - SHA-256 хеш токена).
+// GetSession возвращает ErrNotFound и для отсутствующих, и для истёкших сессий.
+type SessionStore interface {
+	SaveSession(session Session) error
+	GetSession(tokenHash string) (*Session, error)
+	DeleteSession(tokenHash string) error
+	GetUserSessions(username string) ([]Session, error)
+}
+
+// UserStore хранит пользователей и их хешированные пароли (ключ - email)
+type UserStore interface {
+	SaveUser(user *User) error
+	GetUser(email string) (*User, error)
+	GetAllUsers() ([]User, error)
+	SaveUserPassword(email, hashedPassword string) error
+	GetUserPassword(email string) (string, error)
+}
+
+// Store - полный набор операций хранения, который используют App, UserManager и Hub
+type Store interface {
+	ChannelStore
+	InvitationStore
+	MessageStore
+	PinStore
+	PreferenceStore
+	ReadMarkerStore
+	SessionStore
+	ThreadStore
+	UserStore
+}
+
+// NewStoreFromEnv выбирает реализацию хранилища по переменной GOTHERMO_STORE
+// ("redis" по умолчанию, "sqlite" или "memory"). Параметры Redis берутся из
+// REDIS_*, путь к файлу SQLite - из SQLITE_PATH.
+func NewStoreFromEnv() Store {
+	switch os.Getenv("GOTHERMO_STORE") {
+	case "sqlite":
+		store, err := NewSQLiteStore(getEnv("SQLITE_PATH", "gothermo.db"))
+		if err != nil {
+			log.Fatalf("Не удалось открыть SQLite: %v", err)
+		}
+		return store
+	case "memory":
+		log.Println("⚠️ Используется хранилище в памяти, данные не сохраняются между запусками")
+		return NewMemoryStore()
+	default:
+		host := getEnv("REDIS_HOST", "localhost")
+		port := getEnv("REDIS_PORT", "6379")
+		db, _ := strconv.Atoi(getEnv("REDIS_DB", "0"))
+		return NewRedisStore(host+":"+port, os.Getenv("REDIS_PASSWORD"), db)
+	}
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// seedHistory сохраняет в канал сообщения m0..m(n-1) с возрастающим временем
+func seedHistory(t *testing.T, s Store, channel string, n int) {
+	t.Helper()
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < n; i++ {
+		msg := Message{
+			ID:        fmt.Sprintf("m%d", i),
+			User:      "al",
+			Text:      fmt.Sprintf("сообщение %d", i),
+			Channel:   channel,
+			Timestamp: base.Add(time.Duration(i) * time.Minute),
+			Reactions: make(map[string][]string),
+		}
+		if err := s.SaveMessage(msg); err != nil {
+			t.Fatalf("SaveMessage(%s): %v", msg.ID, err)
+		}
+	}
+}
+
+func messageIDs(messages []Message) []string {
+	ids := []string{}
+	for _, msg := range messages {
+		ids = append(ids, msg.ID)
+	}
+	return ids
+}
+
+// testStores возвращает пустые хранилища, которые не требуют внешнего
+// сервера: в памяти и SQLite во временном каталоге теста
+func testStores(t *testing.T) map[string]Store {
+	t.Helper()
+	sqlite, err := NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlite.db.Close() })
+	return map[string]Store{"memory": NewMemoryStore(), "sqlite": sqlite}
+}
+
+func TestStoreMessages(t *testing.T) {
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			seedHistory(t, s, "general", 3)
+
+			messages, err := s.GetMessages("general", 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := messageIDs(messages); !reflect.DeepEqual(got, []string{"m1", "m2"}) {
+				t.Errorf("GetMessages = %v, ожидалось [m1 m2]", got)
+			}
+
+			if _, err := s.GetMessage("random", "m1"); err != ErrNotFound {
+				t.Errorf("сообщение найдено в чужом канале: %v", err)
+			}
+
+			msg, err := s.GetMessage("general", "m1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			msg.Text = "исправлено"
+			if err := s.UpdateMessage("general", *msg); err != nil {
+				t.Fatal(err)
+			}
+			modified, err := s.ModifyMessage("general", "m1", func(msg *Message) error {
+				msg.Text += "!"
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if modified.Text != "исправлено!" {
+				t.Errorf("ModifyMessage вернул %q", modified.Text)
+			}
+			stopped := errors.New("отмена")
+			if _, err := s.ModifyMessage("general", "m1", func(msg *Message) error {
+				msg.Text = "не сохранится"
+				return stopped
+			}); err != stopped {
+				t.Errorf("ошибка modify: %v", err)
+			}
+			if msg, _ := s.GetMessage("general", "m1"); msg.Text != "исправлено!" {
+				t.Errorf("сохранён текст %q", msg.Text)
+			}
+
+			if err := s.DeleteMessage("general", "m1"); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.DeleteMessage("general", "m1"); err != ErrNotFound {
+				t.Errorf("повторное удаление: %v", err)
+			}
+			if _, err := s.GetMessage("general", "m1"); err != ErrNotFound {
+				t.Errorf("удалённое сообщение: %v", err)
+			}
+			if _, err := s.ModifyMessage("general", "m1", func(*Message) error { return nil }); err != ErrNotFound {
+				t.Errorf("изменение удалённого: %v", err)
+			}
+			messages, _ = s.GetMessages("general", 10)
+			if got := messageIDs(messages); !reflect.DeepEqual(got, []string{"m0", "m2"}) {
+				t.Errorf("после удаления %v, ожидалось [m0 m2]", got)
+			}
+		})
+	}
+}
+
+func TestStoreChannels(t *testing.T) {
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			channel := Channel{ID: "1", Name: "secret", Members: []string{"al"}, CreatedBy: "al", IsPrivate: true}
+			if err := s.SaveChannel(channel); err != nil {
+				t.Fatal(err)
+			}
+			got, err := s.GetChannel("secret")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.IsPrivate || !reflect.DeepEqual(got.Members, []string{"al"}) {
+				t.Errorf("канал %+v", got)
+			}
+
+			invitation := Invitation{Channel: "secret", Invitee: "bob", InvitedBy: "al"}
+			if err := s.SaveInvitation(invitation); err != nil {
+				t.Fatal(err)
+			}
+			if invitations, _ := s.GetInvitations("bob"); len(invitations) != 1 || invitations[0].InvitedBy != "al" {
+				t.Errorf("приглашения %+v", invitations)
+			}
+			if err := s.DeleteInvitation("secret", "bob"); err != nil {
+				t.Fatal(err)
+			}
+			if invitations, _ := s.GetInvitations("bob"); len(invitations) != 0 {
+				t.Errorf("приглашение не удалено: %+v", invitations)
+			}
+
+			if err := s.DeleteChannel("secret"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.GetChannel("secret"); err != ErrNotFound {
+				t.Errorf("удалённый канал: %v", err)
+			}
+			if channels, _ := s.GetAllChannels(); len(channels) != 0 {
+				t.Errorf("осталось каналов: %d", len(channels))
+			}
+		})
+	}
+}
+
+func TestStoreHistory(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      HistoryQuery
+		wantIDs    []string
+		wantMore   bool
+		wantCursor string
+		wantErr    error
+	}{
+		{"последняя страница", HistoryQuery{Limit: 3}, []string{"m2", "m3", "m9"}, true, "m2", nil},
+		{"вся история", HistoryQuery{Limit: 50}, []string{"m0", "m1", "m2", "m3", "m9"}, false, "m0", nil},
+		{"до курсора", HistoryQuery{Limit: 3, Before: "m2"}, []string{"m0", "m1"}, false, "m0", nil},
+		{"до первого сообщения", HistoryQuery{Limit: 3, Before: "m0"}, []string{}, false, "", nil},
+		{"после курсора", HistoryQuery{Limit: 2, After: "m1"}, []string{"m2", "m3"}, true, "m3", nil},
+		{"после последнего", HistoryQuery{Limit: 2, After: "m9"}, []string{}, false, "", nil},
+		{"до времени", HistoryQuery{Limit: 10, Before: "2026-01-01T12:02:00Z"}, []string{"m0", "m1"}, false, "m0", nil},
+		{"после времени", HistoryQuery{Limit: 10, After: "2026-01-01T12:02:00Z"}, []string{"m3", "m9"}, false, "m9", nil},
+		{"неизвестный курсор", HistoryQuery{Limit: 3, Before: "m5"}, nil, false, "", ErrNotFound},
+	}
+
+	for name, s := range testStores(t) {
+		seedHistory(t, s, "general", 10)
+		for _, id := range []string{"m4", "m5", "m6", "m7", "m8"} {
+			if err := s.DeleteMessage("general", id); err != nil {
+				t.Fatalf("%s: DeleteMessage(%s): %v", name, id, err)
+			}
+		}
+
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				page, err := s.GetMessageHistory("general", tt.query)
+				if err != tt.wantErr {
+					t.Fatalf("ошибка %v, ожидалась %v", err, tt.wantErr)
+				}
+				if err != nil {
+					return
+				}
+				if got := messageIDs(page.Messages); !reflect.DeepEqual(got, tt.wantIDs) {
+					t.Errorf("сообщения %v, ожидались %v", got, tt.wantIDs)
+				}
+				if page.HasMore != tt.wantMore {
+					t.Errorf("HasMore = %v, ожидалось %v", page.HasMore, tt.wantMore)
+				}
+				if page.NextCursor != tt.wantCursor {
+					t.Errorf("NextCursor = %q, ожидался %q", page.NextCursor, tt.wantCursor)
+				}
+			})
+		}
+	}
+}
+
+// Прокрутка назад по курсорам должна пройти всю историю ровно один раз
+func TestStoreHistoryWalk(t *testing.T) {
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			seedHistory(t, s, "general", 7)
+			s.DeleteMessage("general", "m3")
+
+			var collected []string
+			query := HistoryQuery{Limit: 2}
+			for pages := 0; ; pages++ {
+				if pages > 10 {
+					t.Fatal("прокрутка не закончилась")
+				}
+				page, err := s.GetMessageHistory("general", query)
+				if err != nil {
+					t.Fatal(err)
+				}
+				collected = append(messageIDs(page.Messages), collected...)
+				if !page.HasMore {
+					break
+				}
+				query.Before = page.NextCursor
+			}
+
+			want := []string{"m0", "m1", "m2", "m4", "m5", "m6"}
+			if !reflect.DeepEqual(collected, want) {
+				t.Errorf("собрано %v, ожидалось %v", collected, want)
+			}
+		})
+	}
+}
+
+// База, созданная до колонки ts, получает время сообщений из их JSON,
+// и курсоры по времени работают для старых сообщений
+func TestSQLiteMigrateTimestamps(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "old.db")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`CREATE TABLE messages (
+		seq     INTEGER PRIMARY KEY AUTOINCREMENT,
+		id      TEXT NOT NULL UNIQUE,
+		channel TEXT NOT NULL,
+		data    TEXT NOT NULL
+	)`); err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		data, _ := json.Marshal(Message{ID: fmt.Sprintf("m%d", i), Channel: "general", Timestamp: base.Add(time.Duration(i) * time.Minute)})
+		if _, err := db.Exec(`INSERT INTO messages (id, channel, data) VALUES (?, ?, ?)`, fmt.Sprintf("m%d", i), "general", string(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Close()
+
+	s, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.db.Close()
+
+	page, err := s.GetMessageHistory("general", HistoryQuery{Limit: 10, After: base.Format(time.RFC3339)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := messageIDs(page.Messages); !reflect.DeepEqual(got, []string{"m1", "m2"}) {
+		t.Errorf("после времени %v, ожидалось [m1 m2]", got)
+	}
+}
+
+// Окно из одних удалённых сообщений не означает конец истории
+func TestNewMessagePageEmptyWindow(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    HistoryQuery
+		start    int64
+		end      int64
+		wantMore bool
+	}{
+		{"назад, есть более ранние", HistoryQuery{Limit: 3}, 4, 6, true},
+		{"назад, начало списка", HistoryQuery{Limit: 3}, 0, 2, false},
+		{"вперёд, есть более поздние", HistoryQuery{Limit: 3, After: "m1"}, 2, 4, true},
+		{"вперёд, конец списка", HistoryQuery{Limit: 3, After: "m6"}, 7, 9, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := newMessagePage([]Message{}, tt.query, tt.start, tt.end, 10)
+			if page.HasMore != tt.wantMore {
+				t.Errorf("HasMore = %v, ожидалось %v", page.HasMore, tt.wantMore)
+			}
+			if page.NextCursor != "" {
+				t.Errorf("NextCursor = %q у пустой страницы", page.NextCursor)
+			}
+		})
+	}
+}
+
+func TestToggleReaction(t *testing.T) {
+	tests := []struct {
+		name      string
+		reactions map[string][]string
+		emoji     string
+		username  string
+		want      map[string][]string
+	}{
+		{"первая реакция", nil, "👍", "al", map[string][]string{"👍": {"al"}}},
+		{"второй пользователь", map[string][]string{"👍": {"al"}}, "👍", "bob", map[string][]string{"👍": {"al", "bob"}}},
+		{"снятие своей", map[string][]string{"👍": {"al", "bob"}}, "👍", "al", map[string][]string{"👍": {"bob"}}},
+		{"последняя снятая удаляется", map[string][]string{"👍": {"al"}, "🔥": {"bob"}}, "👍", "al", map[string][]string{"🔥": {"bob"}}},
+		{"другой эмодзи", map[string][]string{"👍": {"al"}}, "🔥", "al", map[string][]string{"👍": {"al"}, "🔥": {"al"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := toggleReaction(tt.reactions, tt.emoji, tt.username)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("реакции %v, ожидались %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Одновременные реакции разных пользователей не теряются
+func TestStoreToggleReactionConcurrent(t *testing.T) {
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			seedHistory(t, s, "general", 1)
+
+			var wg sync.WaitGroup
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					if _, err := s.ToggleReaction("general", "m0", "🔥", fmt.Sprintf("user%d", i)); err != nil {
+						t.Error(err)
+					}
+				}(i)
+			}
+			wg.Wait()
+
+			msg, err := s.GetMessage("general", "m0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(msg.Reactions["🔥"]) != 20 {
+				t.Errorf("сохранилось %d реакций из 20", len(msg.Reactions["🔥"]))
+			}
+			if _, err := s.ToggleReaction("general", "нет", "🔥", "al"); err != ErrNotFound {
+				t.Errorf("реакция на несуществующее сообщение: %v", err)
+			}
+		})
+	}
+}
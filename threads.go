@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const threadSeparator = ":thread:"
+
+// threadChannel - имя списка, в котором хранятся ответы треда. Ответы лежат
+// отдельно от канала, поэтому не засоряют его историю, но редактируются,
+// удаляются и получают реакции теми же методами, что и обычные сообщения.
+func threadChannel(channel, parentID string) string {
+	return channel + threadSeparator + parentID
+}
+
+// splitThreadChannel разбирает имя канала треда на канал и ID корня
+func splitThreadChannel(name string) (channel, parentID string, ok bool) {
+	channel, parentID, ok = strings.Cut(name, threadSeparator)
+	return channel, parentID, ok
+}
+
+// rootChannel возвращает канал, к которому относится канал или тред
+func rootChannel(name string) string {
+	if channel, _, ok := splitThreadChannel(name); ok {
+		return channel
+	}
+	return name
+}
+
+// SendReply отвечает в тред сообщения parentID. Автор ответа и автор корня
+// автоматически подписываются на тред.
+func (a *App) SendReply(user, text, channel, parentID string) (string, error) {
+	if text == "" {
+		return "", fmt.Errorf("сообщение не может быть пустым")
+	}
+	if err := a.checkChannelAccess(channel, user); err != nil {
+		return "", err
+	}
+	parent, err := a.store.GetMessage(channel, parentID)
+	if err == ErrNotFound {
+		return "", fmt.Errorf("сообщение не найдено")
+	}
+	if err != nil {
+		return "", fmt.Errorf("не удалось получить сообщение: %v", err)
+	}
+	if parent.ParentID != "" {
+		return "", fmt.Errorf("нельзя ответить на ответ в треде")
+	}
+
+	reply := Message{ID: uuid.New().String(), User: user, Text: text, Channel: threadChannel(channel, parentID), Timestamp: time.Now(), Reactions: make(map[string][]string), ParentID: parentID}
+	a.resolveMentions(&reply)
+	if err := a.store.SaveMessage(reply); err != nil {
+		return "", fmt.Errorf("не удалось сохранить ответ: %v", err)
+	}
+	a.search.add(reply)
+
+	updatedParent, err := a.store.ModifyMessage(channel, parentID, func(msg *Message) error {
+		msg.ReplyCount++
+		msg.LastReplyAt = &reply.Timestamp
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("не удалось обновить тред: %v", err)
+	}
+
+	for _, follower := range []string{parent.User, user} {
+		if err := a.store.AddThreadFollower(parentID, follower); err != nil {
+			log.Printf("Ошибка подписки %s на тред %s: %v", follower, parentID, err)
+		}
+	}
+	followers, err := a.store.GetThreadFollowers(parentID)
+	if err != nil {
+		log.Printf("Ошибка получения подписчиков треда %s: %v", parentID, err)
+	}
+
+	a.hub.BroadcastThreadReply(ThreadReply{
+		Channel:     channel,
+		ParentID:    parentID,
+		Reply:       reply,
+		ReplyCount:  updatedParent.ReplyCount,
+		LastReplyAt: reply.Timestamp,
+	}, followers)
+	a.notifyMentions(reply)
+	a.notifyDesktop(reply)
+	log.Printf("🧵 %s ответил в тред #%s/%s: %s", user, channel, parentID, truncate(text, 50))
+	return reply.ID, nil
+}
+
+// GetThread возвращает корневое сообщение и последние ответы треда.
+// Более ранние ответы догружаются через GetMessageHistory(parent.channel + тред).
+func (a *App) GetThread(channel, parentID, username string) (Thread, error) {
+	if err := a.checkChannelAccess(channel, username); err != nil {
+		return Thread{}, err
+	}
+	parent, err := a.store.GetMessage(channel, parentID)
+	if err == ErrNotFound {
+		return Thread{}, fmt.Errorf("сообщение не найдено")
+	}
+	if err != nil {
+		return Thread{}, fmt.Errorf("не удалось получить сообщение: %v", err)
+	}
+
+	replies, err := a.store.GetMessageHistory(threadChannel(channel, parentID), HistoryQuery{Limit: defaultHistoryPageSize})
+	if err != nil {
+		return Thread{}, fmt.Errorf("не удалось получить ответы: %v", err)
+	}
+
+	followers, err := a.store.GetThreadFollowers(parentID)
+	if err != nil {
+		log.Printf("Ошибка получения подписчиков треда %s: %v", parentID, err)
+	}
+
+	return Thread{Parent: *parent, Replies: replies, Following: contains(followers, username)}, nil
+}
+
+// FollowThread подписывает пользователя на уведомления об ответах в треде
+func (a *App) FollowThread(channel, parentID, username string) error {
+	if err := a.checkChannelAccess(channel, username); err != nil {
+		return err
+	}
+	if _, err := a.store.GetMessage(channel, parentID); err != nil {
+		return fmt.Errorf("сообщение не найдено")
+	}
+	return a.store.AddThreadFollower(parentID, username)
+}
+
+// UnfollowThread отписывает пользователя от треда
+func (a *App) UnfollowThread(parentID, username string) error {
+	return a.store.RemoveThreadFollower(parentID, username)
+}
+
+// deleteThread удаляет ответы треда parentID, убирает их из поиска и снимает
+// подписки. Вызывается после удаления корня: без него тред уже не открыть,
+// а оставшиеся ответы были бы видны только через историю канала треда.
+func (a *App) deleteThread(channel, parentID string) {
+	if err := a.deleteAllMessages(threadChannel(channel, parentID)); err != nil {
+		log.Printf("Ошибка удаления ответов треда %s: %v", parentID, err)
+	}
+
+	followers, err := a.store.GetThreadFollowers(parentID)
+	if err != nil {
+		log.Printf("Ошибка получения подписчиков треда %s: %v", parentID, err)
+		return
+	}
+	for _, follower := range followers {
+		if err := a.store.RemoveThreadFollower(parentID, follower); err != nil {
+			log.Printf("Ошибка отписки %s от треда %s: %v", follower, parentID, err)
+		}
+	}
+}
+
+// deleteAllMessages удаляет все сообщения канала или треда с конца истории
+// и убирает их из поиска. Треды сообщений канала удаляются вместе с ними.
+func (a *App) deleteAllMessages(channel string) error {
+	for {
+		page, err := a.store.GetMessageHistory(channel, HistoryQuery{Limit: maxHistoryPageSize})
+		if err != nil {
+			return err
+		}
+		for _, msg := range page.Messages {
+			if err := a.store.DeleteMessage(channel, msg.ID); err != nil && err != ErrNotFound {
+				return err
+			}
+			a.search.remove(msg.ID)
+			if msg.ParentID == "" {
+				a.deleteThread(channel, msg.ID)
+			}
+		}
+		if len(page.Messages) == 0 || !page.HasMore {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import "testing"
+
+// Удаление корня треда удаляет и ответы: их не найти ни в истории, ни в поиске
+func TestDeleteThreadRoot(t *testing.T) {
+	app := newTestApp(t, "al", "bob")
+	rootID, err := app.SendMessage("al", "корень треда", "general")
+	if err != nil {
+		t.Fatal(err)
+	}
+	replyID, err := app.SendReply("bob", "ответ в треде", "general", rootID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := app.DeleteMessage(rootID, "general", "al"); err != nil {
+		t.Fatalf("DeleteMessage: %v", err)
+	}
+
+	thread := threadChannel("general", rootID)
+	if _, err := app.store.GetMessage(thread, replyID); err != ErrNotFound {
+		t.Errorf("ответ остался в хранилище: %v", err)
+	}
+	page, err := app.GetMessageHistory(thread, "bob", "", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Messages) != 0 {
+		t.Errorf("в истории треда осталось %d ответов", len(page.Messages))
+	}
+	if _, err := app.GetThread("general", rootID, "bob"); err == nil {
+		t.Error("удалённый тред открылся")
+	}
+	followers, _ := app.store.GetThreadFollowers(rootID)
+	if len(followers) != 0 {
+		t.Errorf("остались подписчики %v", followers)
+	}
+	if hits := app.search.query(searchFilter{terms: []string{"ответ"}}, func(string) bool { return true }); len(hits) != 0 {
+		t.Errorf("ответ остался в поиске")
+	}
+}
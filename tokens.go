@@ -0,0 +1,125 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	tokenIssuer           = "gothermo"
+	defaultAccessTokenTTL = 15 * time.Minute
+)
+
+// AccessClaims - содержимое access-токена. Subject - имя пользователя,
+// SessionID - семейство refresh-токенов, из которого выдан токен.
+type AccessClaims struct {
+	Email     string `json:"email"`
+	SessionID string `json:"sid"`
+	jwt.RegisteredClaims
+}
+
+// keyRing - ключи подписи HS256. Новые токены подписываются активным ключом,
+// проверяются любым известным, поэтому ключ можно сменить, не разлогинив
+// пользователей: старый секрет переносится в JWT_PREVIOUS_SECRETS.
+type keyRing struct {
+	activeID string
+	keys     map[string][]byte // kid -> секрет
+}
+
+// keyID выводит идентификатор ключа из самого секрета, чтобы его не нужно
+// было настраивать отдельно
+func keyID(secret []byte) string {
+	sum := sha256.Sum256(secret)
+	return hex.EncodeToString(sum[:8])
+}
+
+// loadKeyRing читает JWT_SECRET и JWT_PREVIOUS_SECRETS (через запятую).
+// Без JWT_SECRET генерируется временный ключ: access-токены перестанут
+// проходить проверку после перезапуска, и клиенту придётся обновить их
+// по refresh-токену.
+func loadKeyRing() *keyRing {
+	ring := &keyRing{keys: make(map[string][]byte)}
+
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		generated, err := generateToken()
+		if err != nil {
+			log.Fatalf("Не удалось создать ключ подписи: %v", err)
+		}
+		secret = generated
+		log.Println("⚠️ JWT_SECRET не задан, используется временный ключ подписи")
+	}
+	ring.activeID = keyID([]byte(secret))
+	ring.keys[ring.activeID] = []byte(secret)
+
+	for _, previous := range strings.Split(os.Getenv("JWT_PREVIOUS_SECRETS"), ",") {
+		if previous = strings.TrimSpace(previous); previous != "" {
+			ring.keys[keyID([]byte(previous))] = []byte(previous)
+		}
+	}
+	return ring
+}
+
+// accessTokenTTL читает время жизни access-токена из GOTHERMO_ACCESS_TOKEN_TTL
+func accessTokenTTL() time.Duration {
+	if value := os.Getenv("GOTHERMO_ACCESS_TOKEN_TTL"); value != "" {
+		if ttl, err := time.ParseDuration(value); err == nil && ttl > 0 {
+			return ttl
+		}
+		log.Printf("Неверный GOTHERMO_ACCESS_TOKEN_TTL=%q, используется %v", value, defaultAccessTokenTTL)
+	}
+	return defaultAccessTokenTTL
+}
+
+// sign выпускает access-токен для сессии
+func (r *keyRing) sign(session Session) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(accessTokenTTL())
+	claims := AccessClaims{
+		Email:     session.Email,
+		SessionID: session.ID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			Issuer:    tokenIssuer,
+			Subject:   session.Username,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = r.activeID
+	signed, err := token.SignedString(r.keys[r.activeID])
+	return signed, expiresAt, err
+}
+
+// parse проверяет подпись и срок действия access-токена
+func (r *keyRing) parse(tokenString string, options ...jwt.ParserOption) (*AccessClaims, error) {
+	options = append(options, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(tokenIssuer), jwt.WithExpirationRequired())
+
+	claims := &AccessClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		secret, exists := r.keys[kid]
+		if !exists {
+			return nil, fmt.Errorf("неизвестный ключ подписи %q", kid)
+		}
+		return secret, nil
+	}, options...)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" || claims.SessionID == "" {
+		return nil, fmt.Errorf("в токене нет пользователя или сессии")
+	}
+	return claims, nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Токены, подписанные прежним ключом, проверяются, пока ключ перечислен
+// в JWT_PREVIOUS_SECRETS
+func TestKeyRotation(t *testing.T) {
+	session := Session{ID: "family", Username: "al", Email: "al@example.com"}
+
+	t.Setenv("JWT_SECRET", "old-secret")
+	t.Setenv("JWT_PREVIOUS_SECRETS", "")
+	oldToken, _, err := loadKeyRing().sign(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		active   string
+		previous string
+		wantOK   bool
+	}{
+		{"тот же ключ", "old-secret", "", true},
+		{"прежний ключ в списке", "new-secret", "other, old-secret", true},
+		{"прежний ключ убран", "new-secret", "other", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("JWT_SECRET", tt.active)
+			t.Setenv("JWT_PREVIOUS_SECRETS", tt.previous)
+			ring := loadKeyRing()
+
+			claims, err := ring.parse(oldToken)
+			if (err == nil) != tt.wantOK {
+				t.Fatalf("parse: %v, ожидался успех: %v", err, tt.wantOK)
+			}
+			if err == nil && (claims.Subject != "al" || claims.SessionID != "family") {
+				t.Errorf("claims %+v", claims)
+			}
+
+			// новые токены подписываются активным ключом
+			newToken, _, err := ring.sign(session)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ring.parse(newToken); err != nil {
+				t.Errorf("свежий токен не проходит проверку: %v", err)
+			}
+		})
+	}
+}
+
+func TestAccessTokenRejected(t *testing.T) {
+	t.Setenv("JWT_SECRET", "secret")
+	t.Setenv("GOTHERMO_ACCESS_TOKEN_TTL", "1ns")
+	ring := loadKeyRing()
+	expired, _, err := ring.sign(Session{ID: "family", Username: "al"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+
+	t.Setenv("GOTHERMO_ACCESS_TOKEN_TTL", "")
+	valid, _, _ := ring.sign(Session{ID: "family", Username: "al"})
+
+	for name, token := range map[string]string{
+		"истёк":      expired,
+		"подделан":   valid[:len(valid)-2] + "xx",
+		"не JWT":     "not-a-token",
+		"без сессии": mustSign(t, ring, Session{Username: "al"}),
+	} {
+		if _, err := ring.parse(token); err == nil {
+			t.Errorf("%s: токен принят", name)
+		}
+	}
+}
+
+func mustSign(t *testing.T, ring *keyRing, session Session) string {
+	t.Helper()
+	token, _, err := ring.sign(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// typingTTL - через сколько после последнего typing_start индикатор
+	// гаснет сам, если клиент не прислал typing_stop (упал или потерял сеть)
+	typingTTL = 6 * time.Second
+
+	// typingThrottle - не чаще этого интервала typing_start пользователя
+	// в канале рассылается повторно, остальные только продлевают индикатор
+	typingThrottle = 3 * time.Second
+
+	typingSweepInterval = time.Second
+)
+
+// TypingEvent - пользователь начал или перестал печатать в канале
+type TypingEvent struct {
+	Channel  string `json:"channel"`
+	Username string `json:"username"`
+	Typing   bool   `json:"typing"`
+}
+
+type typingState struct {
+	expiresAt time.Time
+	sentAt    time.Time // когда индикатор последний раз рассылался
+}
+
+// typingTracker хранит, кто сейчас печатает: канал -> пользователь -> состояние
+type typingTracker struct {
+	channels map[string]map[string]*typingState
+	mu       sync.Mutex
+}
+
+func newTypingTracker() *typingTracker {
+	return &typingTracker{channels: make(map[string]map[string]*typingState)}
+}
+
+// start продлевает индикатор и сообщает, нужно ли разослать событие
+func (t *typingTracker) start(channel, username string, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.channels[channel] == nil {
+		t.channels[channel] = make(map[string]*typingState)
+	}
+	state, exists := t.channels[channel][username]
+	if !exists {
+		state = &typingState{}
+		t.channels[channel][username] = state
+	}
+	state.expiresAt = now.Add(typingTTL)
+
+	if exists && now.Sub(state.sentAt) < typingThrottle {
+		return false
+	}
+	state.sentAt = now
+	return true
+}
+
+// stop снимает индикатор и сообщает, был ли он включён
+func (t *typingTracker) stop(channel, username string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, exists := t.channels[channel][username]; !exists {
+		return false
+	}
+	delete(t.channels[channel], username)
+	if len(t.channels[channel]) == 0 {
+		delete(t.channels, channel)
+	}
+	return true
+}
+
+// expired снимает просроченные индикаторы и возвращает их
+func (t *typingTracker) expired(now time.Time) []TypingEvent {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	events := []TypingEvent{}
+	for channel, users := range t.channels {
+		for username, state := range users {
+			if now.After(state.expiresAt) {
+				delete(users, username)
+				events = append(events, TypingEvent{Channel: channel, Username: username})
+			}
+		}
+		if len(users) == 0 {
+			delete(t.channels, channel)
+		}
+	}
+	return events
+}
+
+// channelsOf возвращает каналы, в которых пользователь сейчас печатает
+func (t *typingTracker) channelsOf(username string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	channels := []string{}
+	for channel, users := range t.channels {
+		if _, exists := users[username]; exists {
+			channels = append(channels, channel)
+		}
+	}
+	return channels
+}
+
+// StartTyping включает индикатор набора. Повторы в пределах typingThrottle
+// только продлевают его, не рассылая событие заново.
+func (h *Hub) StartTyping(channel, username string) {
+	if h.typing.start(channel, username, time.Now()) {
+		h.sendTyping(TypingEvent{Channel: channel, Username: username, Typing: true})
+	}
+}
+
+// StopTyping гасит индикатор набора, если он был включён
+func (h *Hub) StopTyping(channel, username string) {
+	if h.typing.stop(channel, username) {
+		h.sendTyping(TypingEvent{Channel: channel, Username: username, Typing: false})
+	}
+}
+
+// stopAllTyping гасит все индикаторы пользователя, например когда закрылось
+// его последнее подключение
+func (h *Hub) stopAllTyping(username string) {
+	for _, channel := range h.typing.channelsOf(username) {
+		h.StopTyping(channel, username)
+	}
+}
+
+func (h *Hub) expireTyping() {
+	for _, event := range h.typing.expired(time.Now()) {
+		h.sendTyping(event)
+	}
+}
+
+// sendTyping рассылает индикатор подписчикам канала, кроме самого автора.
+// Событие мимолётное, поэтому не нумеруется и не досылается после обрыва.
+func (h *Hub) sendTyping(event TypingEvent) {
+	data, err := json.Marshal(WSMessage{Type: "typing", Payload: event})
+	if err != nil {
+		log.Printf("Ошибка маршалинга события typing: %v", err)
+		return
+	}
+
+	root := rootChannel(event.Channel)
+	ch, err := h.store.GetChannel(root)
+	if err != nil {
+		ch = nil
+	}
+
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	for username, connections := range h.clients {
+		if username == event.Username {
+			continue
+		}
+		for client := range connections {
+			if client.canReceive(ch, event.Channel, root, nil) {
+				client.deliver(data)
+			}
+		}
+	}
+}
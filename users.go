@@ -16,17 +16,28 @@ type User struct {
 	IsOnline bool   `json:"isOnline"`
 	Status   string `json:"status"` // "online", "away", "offline"
 	LastSeen string `json:"lastSeen,omitempty"`
+
+	// Заполняются только в ответах Login/Register/RefreshSession
+	Token        string `json:"token,omitempty"` // access-токен (JWT)
+	RefreshToken string `json:"refreshToken,omitempty"`
 }
 
 type UserManager struct {
-	users      map[string]*User
-	userTokens map[string]string
-	mu         sync.RWMutex
+	users     map[string]*User
+	store     Store
+	keys      *keyRing
+	mu        sync.RWMutex
+	refreshMu sync.Mutex // сериализует обмен refresh-токенов
 }
 
-var userManager = &UserManager{
-	users:      make(map[string]*User),
-	userTokens: make(map[string]string),
+var userManager *UserManager
+
+func NewUserManager(store Store) *UserManager {
+	return &UserManager{
+		users: make(map[string]*User),
+		store: store,
+		keys:  loadKeyRing(),
+	}
 }
 
 func generateID() string {
@@ -42,7 +53,7 @@ func (um *UserManager) ResetAllStatusesToOffline() {
 		user.Status = "offline"
 		user.IsOnline = false
 		user.LastSeen = time.Now().Format(time.RFC3339)
-		go SaveUserToRedis(user)
+		go um.store.SaveUser(user)
 	}
 
 	log.Printf("✓ Все статусы сброшены в offline (%d пользователей)", len(um.users))
@@ -58,7 +69,7 @@ func (um *UserManager) RegisterUser(username, email string) *User {
 		existingUser.Status = "online"
 		existingUser.LastSeen = time.Now().Format(time.RFC3339)
 
-		go SaveUserToRedis(existingUser)
+		go um.store.SaveUser(existingUser)
 		return existingUser
 	}
 
@@ -74,7 +85,7 @@ func (um *UserManager) RegisterUser(username, email string) *User {
 	um.users[username] = user
 	log.Printf("✅ Создан новый пользователь в памяти: %s (ID: %s)", username, user.ID)
 
-	go SaveUserToRedis(user)
+	go um.store.SaveUser(user)
 
 	return user
 }
@@ -101,7 +112,7 @@ func (um *UserManager) UpdateUserStatus(username, status string) bool {
 	user.IsOnline = status != "offline"
 	user.LastSeen = time.Now().Format(time.RFC3339)
 
-	go SaveUserToRedis(user)
+	go um.store.SaveUser(user)
 
 	log.Printf("User %s status updated to: %s", username, status)
 	return true
@@ -118,46 +129,21 @@ func (um *UserManager) GetAllUsers() []User {
 	return users
 }
 
-func (um *UserManager) LoadUsersFromRedis() {
-	usersFromRedis, err := GetAllUsersFromRedis()
+func (um *UserManager) LoadUsersFromStore() {
+	storedUsers, err := um.store.GetAllUsers()
 	if err != nil {
-		log.Printf("Error loading users from Redis: %v", err)
+		log.Printf("Error loading users from store: %v", err)
 		return
 	}
 
 	um.mu.Lock()
 	defer um.mu.Unlock()
 
-	for _, user := range usersFromRedis {
+	for _, user := range storedUsers {
 		um.users[user.Username] = &user
 	}
 
-	log.Printf("Loaded %d users from Redis", len(usersFromRedis))
-}
-
-func (um *UserManager) SetUserToken(token, username string) {
-	um.mu.Lock()
-	defer um.mu.Unlock()
-	um.userTokens[token] = username
-}
-
-func (um *UserManager) GetUserByToken(token string) (*User, bool) {
-	um.mu.RLock()
-	defer um.mu.RUnlock()
-
-	username, exists := um.userTokens[token]
-	if !exists {
-		return nil, false
-	}
-
-	user, userExists := um.users[username]
-	return user, userExists
-}
-
-func (um *UserManager) RemoveUserToken(token string) {
-	um.mu.Lock()
-	defer um.mu.Unlock()
-	delete(um.userTokens, token)
+	log.Printf("Loaded %d users from store", len(storedUsers))
 }
 
 // Wails API
@@ -206,6 +192,7 @@ func (a *App) Logout(token string) bool {
 	user, exists := userManager.GetUserByToken(token)
 	if exists {
 		userManager.UpdateUserStatus(user.Username, "offline")
+		a.notifications.clearLocalUser(user.Username)
 
 		// Broadcast через WebSocket
 		if globalHub != nil {
@@ -19,19 +19,35 @@ type Client struct {
 	Hub      *Hub
 	Send     chan []byte
 	Channels []string
+
+	token  string // access-токен, которым аутентифицировано соединение
+	authMu sync.Mutex
+
+	resume *ResumeState // что клиент получил до переподключения
 }
 
 type Hub struct {
-	clients    map[string]*Client // Username -> Client
+	clients    map[string]map[*Client]bool // Username -> все подключения пользователя
 	broadcast  chan []byte
 	register   chan *Client
 	unregister chan *Client
+	store      Store
+	app        *App // операции, которые клиенты вызывают через WebSocket
+	upgrader   websocket.Upgrader
+	events     *eventLog
+	sent       *sendDeduper
+	typing     *typingTracker
 	mutex      sync.RWMutex
 }
 
 type WSMessage struct {
 	Type    string      `json:"type"`
 	Payload interface{} `json:"payload"`
+
+	// Заполняются у событий, которые можно дослать после переподключения
+	// (см. events.go): поток - корневой канал или presenceStream
+	Stream string `json:"stream,omitempty"`
+	Seq    uint64 `json:"seq,omitempty"`
 }
 
 type StatusUpdate struct {
@@ -44,12 +60,52 @@ type ChannelMessage struct {
 	Message Message `json:"message"`
 }
 
-func NewHub() *Hub {
+type MessageDeleted struct {
+	Channel   string `json:"channel"`
+	MessageID string `json:"messageId"`
+	DeletedBy string `json:"deletedBy"`
+}
+
+type ThreadReply struct {
+	Channel     string    `json:"channel"`
+	ParentID    string    `json:"parentId"`
+	Reply       Message   `json:"reply"`
+	ReplyCount  int       `json:"replyCount"`
+	LastReplyAt time.Time `json:"lastReplyAt"`
+}
+
+type ReactionUpdate struct {
+	Channel   string              `json:"channel"`
+	MessageID string              `json:"messageId"`
+	Emoji     string              `json:"emoji"`
+	Username  string              `json:"username"`
+	Added     bool                `json:"added"`
+	Reactions map[string][]string `json:"reactions"` // итоговые реакции сообщения
+}
+
+// PinUpdate - сообщение закреплено или откреплено; Order - итоговый
+// порядок закреплённых сообщений канала. При Reordered меняется только
+// порядок: MessageID пуст, а Pinned не значит ничего.
+type PinUpdate struct {
+	Channel   string   `json:"channel"`
+	MessageID string   `json:"messageId"`
+	Username  string   `json:"username"`
+	Pinned    bool     `json:"pinned"`
+	Reordered bool     `json:"reordered,omitempty"`
+	Order     []string `json:"order"`
+}
+
+func NewHub(store Store) *Hub {
 	hub := &Hub{
-		clients:    make(map[string]*Client),
+		clients:    make(map[string]map[*Client]bool),
 		broadcast:  make(chan []byte),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
+		store:      store,
+		upgrader:   websocket.Upgrader{ReadBufferSize: 1024, WriteBufferSize: 1024},
+		events:     newEventLog(),
+		sent:       newSendDeduper(),
+		typing:     newTypingTracker(),
 	}
 
 	// ✅ ДОБАВЬТЕ ЭТУ СТРОКУ
@@ -59,18 +115,29 @@ func NewHub() *Hub {
 }
 
 func (h *Hub) Run() {
+	typingTicker := time.NewTicker(typingSweepInterval)
+	defer typingTicker.Stop()
+
 	for {
 		select {
 		case client := <-h.register:
+			// Пока идёт досылка, новые события не рассылаются: клиент
+			// получит их после пропущенных, без дублей и перестановок
+			h.events.mu.Lock()
 			h.mutex.Lock()
-			h.clients[client.Username] = client
+			firstConnection := len(h.clients[client.Username]) == 0
+			if firstConnection {
+				h.clients[client.Username] = make(map[*Client]bool)
+			}
+			h.clients[client.Username][client] = true
 			h.mutex.Unlock()
-			log.Printf("✓ WebSocket клиент подключен: %s", client.Username)
+			log.Printf("✓ WebSocket клиент подключен: %s (подключений: %d)", client.Username, h.ConnectionCount(client.Username))
 
 			welcomeMsg := WSMessage{
 				Type: "connected",
 				Payload: map[string]string{
 					"message": "Connected to WebSocket",
+					"epoch":   h.events.epoch,
 				},
 			}
 			data, _ := json.Marshal(welcomeMsg)
@@ -84,43 +151,41 @@ func (h *Hub) Run() {
 			usersData, _ := json.Marshal(usersListMsg)
 			client.Send <- usersData
 
-			statusMsg := WSMessage{
-				Type: "status_update",
-				Payload: StatusUpdate{
-					Username: client.Username,
-					Status:   "online",
-				},
+			h.replayTo(client, client.resume)
+			h.events.mu.Unlock()
+
+			// Остальные устройства пользователя уже держат его онлайн
+			if firstConnection {
+				h.userConnected(client.Username)
 			}
-			statusData, _ := json.Marshal(statusMsg)
-			h.broadcastMessage(statusData, client.Username)
 
 		case client := <-h.unregister:
 			h.mutex.Lock()
-			if _, ok := h.clients[client.Username]; ok {
-				delete(h.clients, client.Username)
+			lastConnection := false
+			if connections, ok := h.clients[client.Username]; ok && connections[client] {
+				delete(connections, client)
 				close(client.Send)
-				log.Printf("✗ WebSocket клиент отключен: %s", client.Username)
-
-				statusMsg := WSMessage{
-					Type: "status_update",
-					Payload: StatusUpdate{
-						Username: client.Username,
-						Status:   "offline",
-					},
+				if len(connections) == 0 {
+					delete(h.clients, client.Username)
+					lastConnection = true
 				}
-				data, _ := json.Marshal(statusMsg)
-				h.broadcastMessage(data, client.Username)
+				log.Printf("✗ WebSocket клиент отключен: %s (осталось подключений: %d)", client.Username, len(connections))
 			}
 			h.mutex.Unlock()
 
+			if lastConnection {
+				h.stopAllTyping(client.Username)
+				h.userDisconnected(client.Username)
+			}
+
+		case <-typingTicker.C:
+			h.expireTyping()
+
 		case message := <-h.broadcast:
 			h.mutex.RLock()
-			for _, client := range h.clients {
-				select {
-				case client.Send <- message:
-				default:
-					close(client.Send)
-					delete(h.clients, client.Username)
+			for _, connections := range h.clients {
+				for client := range connections {
+					client.deliver(message)
 				}
 			}
 			h.mutex.RUnlock()
@@ -128,10 +193,60 @@ func (h *Hub) Run() {
 	}
 }
 
-func (h *Hub) BroadcastToChannel(channel string, msg Message) {
+// userConnected переводит пользователя в онлайн при первом подключении.
+// Статус "away", выставленный вручную, сохраняется.
+func (h *Hub) userConnected(username string) {
+	status := "online"
+	if user, exists := userManager.GetUser(username); exists && user.Status == "away" {
+		status = user.Status
+	} else {
+		userManager.UpdateUserStatus(username, status)
+	}
+
+	h.sendPresence(WSMessage{
+		Type: "status_update",
+		Payload: StatusUpdate{
+			Username: username,
+			Status:   status,
+		},
+	}, username)
+}
+
+// userDisconnected переводит пользователя в офлайн, когда закрылось
+// последнее из его подключений
+func (h *Hub) userDisconnected(username string) {
+	userManager.UpdateUserStatus(username, "offline")
+
+	h.sendPresence(WSMessage{
+		Type: "status_update",
+		Payload: StatusUpdate{
+			Username: username,
+			Status:   "offline",
+		},
+	}, username)
+}
+
+// deliver кладёт событие в очередь клиента. Клиент, который не успевает
+// читать, отключается через unregister (вызывается под RLock хаба,
+// поэтому удалять его из карты здесь нельзя).
+func (c *Client) deliver(data []byte) bool {
+	select {
+	case c.Send <- data:
+		return true
+	default:
+		go func() { c.Hub.unregister <- c }()
+		return false
+	}
+}
+
+// ConnectionCount возвращает число открытых подключений пользователя
+func (h *Hub) ConnectionCount(username string) int {
 	h.mutex.RLock()
 	defer h.mutex.RUnlock()
+	return len(h.clients[username])
+}
 
+func (h *Hub) BroadcastToChannel(channel string, msg Message) {
 	channelMsg := WSMessage{
 		Type: "channel_message",
 		Payload: ChannelMessage{
@@ -140,112 +255,192 @@ func (h *Hub) BroadcastToChannel(channel string, msg Message) {
 		},
 	}
 
-	data, err := json.Marshal(channelMsg)
+	log.Printf("📢 Вещаем в канал #%s: %s", channel, truncateText(msg.Text, 50))
+
+	// Отправленное сообщение завершает набор
+	h.StopTyping(channel, msg.User)
+
+	sentCount := h.sendToChannel(channel, channelMsg)
+	log.Printf("✓ Сообщение отправлено %d клиентам в канале #%s", sentCount, channel)
+}
+
+// SendToUser отправляет событие всем подключениям пользователя
+func (h *Hub) SendToUser(username string, wsMsg WSMessage) {
+	data, err := json.Marshal(wsMsg)
 	if err != nil {
-		log.Printf("Ошибка маршалинга сообщения для канала: %v", err)
+		log.Printf("Ошибка маршалинга события %s: %v", wsMsg.Type, err)
 		return
 	}
 
-	log.Printf("📢 Вещаем в канал #%s: %s", channel, truncateText(msg.Text, 50))
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
 
-	sentCount := 0
-	for username, client := range h.clients {
+	for client := range h.clients[username] {
+		client.deliver(data)
+	}
+}
+
+func (h *Hub) BroadcastMessageEdited(channel string, msg Message) {
+	h.sendToChannel(channel, WSMessage{
+		Type: "message_edited",
+		Payload: ChannelMessage{
+			Channel: channel,
+			Message: msg,
+		},
+	})
+}
+
+func (h *Hub) BroadcastMessageDeleted(deleted MessageDeleted) {
+	h.sendToChannel(deleted.Channel, WSMessage{
+		Type:    "message_deleted",
+		Payload: deleted,
+	})
+}
+
+// BroadcastThreadReply уведомляет подписчиков канала (чтобы обновить счётчик
+// ответов) и подписчиков треда, даже если они не следят за каналом
+func (h *Hub) BroadcastThreadReply(reply ThreadReply, followers []string) {
+	h.StopTyping(reply.Reply.Channel, reply.Reply.User)
+	h.sendToAudience(reply.Channel, WSMessage{
+		Type:    "thread_reply",
+		Payload: reply,
+	}, followers)
+}
+
+// BroadcastDirectMessageOpened сообщает участникам о новой личной переписке
+func (h *Hub) BroadcastDirectMessageOpened(channel Channel) {
+	h.sendToChannel(channel.Name, WSMessage{
+		Type:    "dm_opened",
+		Payload: channel,
+	})
+}
+
+func (h *Hub) BroadcastReactionUpdate(update ReactionUpdate) {
+	h.sendToChannel(update.Channel, WSMessage{
+		Type:    "reaction_update",
+		Payload: update,
+	})
+}
+
+func (h *Hub) BroadcastPinUpdate(update PinUpdate) {
+	h.sendToChannel(update.Channel, WSMessage{
+		Type:    "pin_update",
+		Payload: update,
+	})
+}
+
+// sendToChannel рассылает событие подписчикам канала и возвращает число получателей.
+// События тредов получают подписчики канала, к которому относится тред.
+func (h *Hub) sendToChannel(channel string, wsMsg WSMessage) int {
+	return h.sendToAudience(channel, wsMsg, nil)
+}
+
+// sendToAudience рассылает событие канала тем, кто может его получить:
+// в личных переписках - только участникам, независимо от подписок,
+// в остальных каналах - подписчикам и дополнительно пользователям extra.
+// Событие получает номер в потоке корневого канала.
+func (h *Hub) sendToAudience(channel string, wsMsg WSMessage, extra []string) int {
+	root := rootChannel(channel)
+	ch, err := h.store.GetChannel(root)
+	if err != nil {
+		ch = nil
+	}
+
+	h.events.mu.Lock()
+	defer h.events.mu.Unlock()
 
-		if len(client.Channels) == 0 || contains(client.Channels, channel) {
-			select {
-			case client.Send <- data:
+	data, err := h.events.append(root, channel, extra, wsMsg)
+	if err != nil {
+		log.Printf("Ошибка маршалинга события %s для канала: %v", wsMsg.Type, err)
+		return 0
+	}
+
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	sentCount := 0
+	for _, connections := range h.clients {
+		for client := range connections {
+			if client.canReceive(ch, channel, root, extra) && client.deliver(data) {
 				sentCount++
-			default:
-				close(client.Send)
-				delete(h.clients, username)
 			}
 		}
 	}
 
-	log.Printf("✓ Сообщение отправлено %d клиентам в канале #%s", sentCount, channel)
+	return sentCount
 }
 
+func (c *Client) canReceive(ch *Channel, channel, root string, extra []string) bool {
+	if ch != nil && ch.IsPrivate && !contains(ch.Members, c.Username) {
+		return false
+	}
+	if ch != nil && ch.IsDirect {
+		return true
+	}
+	if contains(extra, c.Username) {
+		return true
+	}
+	return len(c.Channels) == 0 || contains(c.Channels, channel) || contains(c.Channels, root)
+}
+
+// AddChannelToClient подписывает на канал все подключения пользователя
 func (h *Hub) AddChannelToClient(username, channel string) {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
 
-	if client, exists := h.clients[username]; exists {
-		if !contains(client.Channels, channel) {
-			client.Channels = append(client.Channels, channel)
-			log.Printf("✅ Клиент %s подписан на канал #%s", username, channel)
-		}
+	for client := range h.clients[username] {
+		client.subscribe(channel)
 	}
 }
 
+// RemoveChannelFromClient отписывает от канала все подключения пользователя
 func (h *Hub) RemoveChannelFromClient(username, channel string) {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
 
-	if client, exists := h.clients[username]; exists {
-		for i, ch := range client.Channels {
-			if ch == channel {
-				client.Channels = append(client.Channels[:i], client.Channels[i+1:]...)
-				log.Printf("❌ Клиент %s отписан от канала #%s", username, channel)
-				break
-			}
+	for client := range h.clients[username] {
+		client.unsubscribe(channel)
+	}
+}
+
+// subscribe и unsubscribe меняют подписки одного подключения,
+// вызываются под мьютексом хаба
+func (c *Client) subscribe(channel string) {
+	if !contains(c.Channels, channel) {
+		c.Channels = append(c.Channels, channel)
+		log.Printf("✅ Клиент %s (%s) подписан на канал #%s", c.Username, c.ID, channel)
+	}
+}
+
+func (c *Client) unsubscribe(channel string) {
+	for i, ch := range c.Channels {
+		if ch == channel {
+			c.Channels = append(c.Channels[:i], c.Channels[i+1:]...)
+			log.Printf("❌ Клиент %s (%s) отписан от канала #%s", c.Username, c.ID, channel)
+			break
 		}
 	}
 }
 
 func (h *Hub) BroadcastStatusUpdate(username, status string) {
-	statusMsg := WSMessage{
+	sentCount := h.sendPresence(WSMessage{
 		Type: "status_update",
 		Payload: StatusUpdate{
 			Username: username,
 			Status:   status,
 		},
-	}
-
-	data, err := json.Marshal(statusMsg)
-	if err != nil {
-		log.Printf("Ошибка маршалинга статуса: %v", err)
-		return
-	}
-
-	h.mutex.RLock()
-	defer h.mutex.RUnlock()
-
-	for _, client := range h.clients {
-		select {
-		case client.Send <- data:
-		default:
-			close(client.Send)
-			delete(h.clients, client.Username)
-		}
-	}
+	}, "")
 
 	log.Printf("📢 Статус обновлен: %s -> %s (отправлено %d клиентам)",
-		username, status, len(h.clients))
+		username, status, sentCount)
 }
 
 func (h *Hub) BroadcastNewMessage(channel string, message Message) {
 	h.BroadcastToChannel(channel, message)
 }
 
-func (h *Hub) broadcastMessage(message []byte, excludeUsername string) {
-	h.mutex.RLock()
-	defer h.mutex.RUnlock()
-
-	for username, client := range h.clients {
-		if username == excludeUsername {
-			continue
-		}
-
-		select {
-		case client.Send <- message:
-		default:
-			close(client.Send)
-			delete(h.clients, username)
-		}
-	}
-}
-
-func (h *Hub) HandleClient(conn *websocket.Conn, username string) {
+// handleClient регистрирует уже аутентифицированное соединение
+func (h *Hub) handleClient(conn *websocket.Conn, username, token string, resume *ResumeState) {
 	client := &Client{
 		ID:       fmt.Sprintf("%d", time.Now().UnixNano()),
 		Username: username,
@@ -253,30 +448,36 @@ func (h *Hub) HandleClient(conn *websocket.Conn, username string) {
 		Hub:      h,
 		Send:     make(chan []byte, 256),
 		Channels: []string{},
+		token:    token,
+		resume:   resume,
 	}
 
 	h.register <- client
 
-	go h.autoSubscribeChannels(username)
+	go h.autoSubscribeChannels(client)
 
 	go client.readPump()
 
 	go client.writePump()
 }
 
-func (h *Hub) autoSubscribeChannels(username string) {
+// autoSubscribeChannels подписывает новое подключение на доступные каналы
+func (h *Hub) autoSubscribeChannels(client *Client) {
 
 	time.Sleep(100 * time.Millisecond)
 
-	channels, err := GetAllChannels()
+	channels, err := h.store.GetAllChannels()
 	if err != nil {
 		log.Printf("Ошибка получения каналов для автоматической подписки: %v", err)
 		return
 	}
 
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
 	for _, channel := range channels {
-		if !channel.IsPrivate {
-			h.AddChannelToClient(username, channel.Name)
+		if !channel.IsPrivate || contains(channel.Members, client.Username) {
+			client.subscribe(channel.Name)
 		}
 	}
 }
@@ -319,12 +520,17 @@ func (c *Client) handleMessage(msg WSMessage) {
 
 	case "subscribe_channel":
 		if channel, ok := msg.Payload.(string); ok {
-			c.Hub.AddChannelToClient(c.Username, channel)
+			err := c.Hub.app.checkChannelAccess(channel, c.Username)
+			if err == nil {
+				c.Hub.mutex.Lock()
+				c.subscribe(channel)
+				c.Hub.mutex.Unlock()
+			}
 			response := WSMessage{
 				Type: "subscribed",
 				Payload: map[string]interface{}{
 					"channel": channel,
-					"success": true,
+					"success": err == nil,
 				},
 			}
 			data, _ := json.Marshal(response)
@@ -333,21 +539,16 @@ func (c *Client) handleMessage(msg WSMessage) {
 
 	case "unsubscribe_channel":
 		if channel, ok := msg.Payload.(string); ok {
-			c.Hub.RemoveChannelFromClient(c.Username, channel)
+			c.Hub.mutex.Lock()
+			c.unsubscribe(channel)
+			c.Hub.mutex.Unlock()
 		}
 
 	case "status_change":
-
-		payloadBytes, err := json.Marshal(msg.Payload)
-		if err != nil {
-			log.Printf("Ошибка маршалинга payload: %v", err)
-			return
-		}
-
 		var statusPayload struct {
 			Status string `json:"status"`
 		}
-		if err := json.Unmarshal(payloadBytes, &statusPayload); err != nil {
+		if err := decodePayload(msg.Payload, &statusPayload); err != nil {
 			log.Printf("Ошибка парсинга status_change: %v", err)
 			return
 		}
@@ -358,9 +559,113 @@ func (c *Client) handleMessage(msg WSMessage) {
 			log.Printf("🔄 Статус изменен через WebSocket: %s -> %s",
 				c.Username, statusPayload.Status)
 		}
+
+	case "edit_message":
+		var editPayload struct {
+			MessageID string `json:"messageId"`
+			Channel   string `json:"channel"`
+			Text      string `json:"text"`
+		}
+		if err := decodePayload(msg.Payload, &editPayload); err != nil {
+			log.Printf("Ошибка парсинга edit_message: %v", err)
+			return
+		}
+
+		err := c.Hub.app.EditMessage(editPayload.MessageID, editPayload.Channel, c.Username, editPayload.Text)
+		if err != nil {
+			c.sendError(msg.Type, err)
+		}
+
+	case "delete_message":
+		var deletePayload struct {
+			MessageID string `json:"messageId"`
+			Channel   string `json:"channel"`
+		}
+		if err := decodePayload(msg.Payload, &deletePayload); err != nil {
+			log.Printf("Ошибка парсинга delete_message: %v", err)
+			return
+		}
+
+		if err := c.Hub.app.DeleteMessage(deletePayload.MessageID, deletePayload.Channel, c.Username); err != nil {
+			c.sendError(msg.Type, err)
+		}
+
+	case "typing_start", "typing_stop":
+		var typingPayload struct {
+			Channel string `json:"channel"`
+		}
+		if err := decodePayload(msg.Payload, &typingPayload); err != nil || typingPayload.Channel == "" {
+			return
+		}
+
+		if msg.Type == "typing_stop" {
+			c.Hub.StopTyping(typingPayload.Channel, c.Username)
+			return
+		}
+		if err := c.Hub.app.checkChannelAccess(typingPayload.Channel, c.Username); err != nil {
+			c.sendError(msg.Type, err)
+			return
+		}
+		c.Hub.StartTyping(typingPayload.Channel, c.Username)
+
+	case "mark_read":
+		var readPayload struct {
+			Channel   string `json:"channel"`
+			MessageID string `json:"messageId"`
+		}
+		if err := decodePayload(msg.Payload, &readPayload); err != nil {
+			log.Printf("Ошибка парсинга mark_read: %v", err)
+			return
+		}
+
+		if _, err := c.Hub.app.MarkRead(readPayload.Channel, c.Username, readPayload.MessageID); err != nil {
+			c.sendError(msg.Type, err)
+		}
+
+	case "send_message":
+		c.handleSendMessage(msg.Payload)
+
+	case "reauth":
+		var reauthPayload struct {
+			Token string `json:"token"`
+		}
+		if err := decodePayload(msg.Payload, &reauthPayload); err != nil {
+			log.Printf("Ошибка парсинга reauth: %v", err)
+			return
+		}
+
+		expiresAt, err := c.reauthenticate(reauthPayload.Token)
+		if err != nil {
+			c.sendError(msg.Type, err)
+			return
+		}
+		c.sendEvent(WSMessage{
+			Type:    "reauthenticated",
+			Payload: map[string]interface{}{"expiresAt": expiresAt},
+		})
 	}
 }
 
+// decodePayload перекладывает произвольный payload в типизированную структуру
+func decodePayload(payload interface{}, v interface{}) error {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(payloadBytes, v)
+}
+
+// sendError сообщает клиенту, что операция не выполнена
+func (c *Client) sendError(op string, err error) {
+	c.sendEvent(WSMessage{
+		Type: "error",
+		Payload: map[string]string{
+			"op":      op,
+			"message": err.Error(),
+		},
+	})
+}
+
 func (c *Client) writePump() {
 	ticker := time.NewTicker(30 * time.Second)
 	defer func() {
@@ -378,23 +683,16 @@ func (c *Client) writePump() {
 				return
 			}
 
-			w, err := c.Conn.NextWriter(websocket.TextMessage)
-			if err != nil {
+			// Каждое событие - отдельный кадр: клиент разбирает кадр как
+			// один JSON, склеенные через перевод строки события он потеряет
+			if err := c.Conn.WriteMessage(websocket.TextMessage, message); err != nil {
 				return
 			}
-			w.Write(message)
 
-			n := len(c.Send)
-			for i := 0; i < n; i++ {
-				w.Write([]byte{'\n'})
-				w.Write(<-c.Send)
-			}
-
-			if err := w.Close(); err != nil {
+		case <-ticker.C:
+			if !c.revalidate() {
 				return
 			}
-
-		case <-ticker.C:
 			c.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
 			if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
 				return
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+// Ответ с ошибкой отключённому клиенту не должен ронять readPump
+func TestSendErrorAfterDisconnect(t *testing.T) {
+	hub := NewHub(NewMemoryStore())
+	client := &Client{Username: "al", Hub: hub, Send: make(chan []byte, 1)}
+	hub.clients["al"] = map[*Client]bool{client: true}
+
+	client.sendError("edit_message", errors.New("нет доступа"))
+	if len(client.Send) != 1 {
+		t.Fatal("подключённый клиент не получил ошибку")
+	}
+
+	delete(hub.clients, "al")
+	close(client.Send)
+	client.sendError("edit_message", errors.New("нет доступа"))
+}
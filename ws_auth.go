@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/websocket"
+)
+
+// Коды закрытия WebSocket при ошибках аутентификации (диапазон 4000-4999
+// отведён под коды приложения)
+const (
+	closeAuthRequired   = 4001 // токен не передан или недействителен
+	closeTokenExpired   = 4002 // токен истёк: обновите его и переподключитесь
+	closeSessionRevoked = 4003 // сессия отозвана, нужен повторный вход
+)
+
+var closeReasons = map[int]string{
+	closeAuthRequired:        "требуется авторизация",
+	closeTokenExpired:        "токен истёк",
+	closeSessionRevoked:      "сессия отозвана",
+	websocket.CloseGoingAway: "сервер остановлен",
+}
+
+// tokenFromRequest достаёт access-токен из параметра token или заголовка
+// Authorization (браузерный WebSocket не умеет передавать заголовки)
+func tokenFromRequest(r *http.Request) string {
+	if token := r.URL.Query().Get("token"); token != "" {
+		return token
+	}
+	if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		return strings.TrimPrefix(header, "Bearer ")
+	}
+	return ""
+}
+
+// resumeFromRequest разбирает параметр resume - JSON с ResumeState,
+// который клиент передаёт при переподключении
+func resumeFromRequest(r *http.Request) *ResumeState {
+	value := r.URL.Query().Get("resume")
+	if value == "" {
+		return nil
+	}
+
+	var resume ResumeState
+	if err := json.Unmarshal([]byte(value), &resume); err != nil {
+		log.Printf("Неверный параметр resume: %v", err)
+		return nil
+	}
+	return &resume
+}
+
+func authCloseCode(err error) int {
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return closeTokenExpired
+	case errors.Is(err, errSessionRevoked):
+		return closeSessionRevoked
+	default:
+		return closeAuthRequired
+	}
+}
+
+// closeWithCode отправляет клиенту кадр закрытия с кодом и рвёт соединение
+func closeWithCode(conn *websocket.Conn, code int) {
+	message := websocket.FormatCloseMessage(code, closeReasons[code])
+	conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(time.Second))
+	conn.Close()
+}
+
+// ServeWS - обработчик /ws. Клиент регистрируется только после проверки
+// токена, имя пользователя берётся из токена.
+func (h *Hub) ServeWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("Ошибка апгрейда WebSocket: %v", err)
+		return
+	}
+
+	if err := h.HandleAuthenticatedClient(conn, tokenFromRequest(r), resumeFromRequest(r)); err != nil {
+		log.Printf("🚫 WebSocket отклонён (%s): %v", r.RemoteAddr, err)
+	}
+}
+
+// HandleAuthenticatedClient подключает клиента по access-токену. При
+// неверном токене соединение закрывается с кодом closeAuthRequired,
+// closeTokenExpired или closeSessionRevoked.
+func (h *Hub) HandleAuthenticatedClient(conn *websocket.Conn, token string, resume *ResumeState) error {
+	if token == "" {
+		closeWithCode(conn, closeAuthRequired)
+		return fmt.Errorf("токен не передан")
+	}
+
+	user, _, err := userManager.Authenticate(token)
+	if err != nil {
+		closeWithCode(conn, authCloseCode(err))
+		return err
+	}
+
+	h.handleClient(conn, user.Username, token, resume)
+	return nil
+}
+
+// revalidate повторно проверяет токен долгоживущего соединения и закрывает
+// его, если токен истёк или сессия отозвана
+func (c *Client) revalidate() bool {
+	c.authMu.Lock()
+	token := c.token
+	c.authMu.Unlock()
+
+	if _, _, err := userManager.Authenticate(token); err != nil {
+		log.Printf("🚫 WebSocket %s закрыт: %v", c.Username, err)
+		closeWithCode(c.Conn, authCloseCode(err))
+		return false
+	}
+	return true
+}
+
+// reauthenticate заменяет токен соединения на обновлённый. Токен должен
+// принадлежать тому же пользователю.
+func (c *Client) reauthenticate(token string) (time.Time, error) {
+	user, claims, err := userManager.Authenticate(token)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if user.Username != c.Username {
+		return time.Time{}, fmt.Errorf("токен выдан другому пользователю")
+	}
+
+	c.authMu.Lock()
+	c.token = token
+	c.authMu.Unlock()
+	return claims.ExpiresAt.Time, nil
+}
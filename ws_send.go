@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// sendDedupTTL - сколько помнить clientId отправленных сообщений. Повтор
+// с тем же clientId в этом окне не создаёт второе сообщение.
+const sendDedupTTL = 10 * time.Minute
+
+// SendAck подтверждает, что сообщение с clientId сохранено
+type SendAck struct {
+	ClientID  string `json:"clientId"`
+	MessageID string `json:"messageId"`
+	Channel   string `json:"channel"`
+	Duplicate bool   `json:"duplicate"` // повтор уже обработанной отправки
+}
+
+// SendNack сообщает, что сообщение с clientId не сохранено
+type SendNack struct {
+	ClientID string `json:"clientId"`
+	Error    string `json:"error"`
+}
+
+type sendResult struct {
+	done      chan struct{} // закрывается, когда отправка завершена
+	messageID string
+	err       error
+	at        time.Time
+}
+
+// sendDeduper помнит результаты send_message по паре (пользователь, clientId)
+type sendDeduper struct {
+	results   map[string]*sendResult
+	lastSweep time.Time
+	mu        sync.Mutex
+}
+
+func newSendDeduper() *sendDeduper {
+	return &sendDeduper{results: make(map[string]*sendResult)}
+}
+
+// do выполняет send один раз на clientId. Одновременные повторы ждут
+// первую попытку и получают её результат. Неудачная попытка забывается,
+// чтобы клиент мог повторить отправку.
+func (d *sendDeduper) do(username, clientID string, send func() (string, error)) (messageID string, duplicate bool, err error) {
+	key := username + "\x00" + clientID
+
+	d.mu.Lock()
+	d.sweep()
+	if result, exists := d.results[key]; exists {
+		d.mu.Unlock()
+		<-result.done
+		return result.messageID, true, result.err
+	}
+	result := &sendResult{done: make(chan struct{}), at: time.Now()}
+	d.results[key] = result
+	d.mu.Unlock()
+
+	result.messageID, result.err = send()
+	if result.err != nil {
+		d.mu.Lock()
+		delete(d.results, key)
+		d.mu.Unlock()
+	}
+	close(result.done)
+	return result.messageID, false, result.err
+}
+
+// sweep удаляет устаревшие записи не чаще раза в минуту, вызывается под d.mu
+func (d *sendDeduper) sweep() {
+	now := time.Now()
+	if now.Sub(d.lastSweep) < time.Minute {
+		return
+	}
+	d.lastSweep = now
+
+	for key, result := range d.results {
+		select {
+		case <-result.done:
+			if now.Sub(result.at) > sendDedupTTL {
+				delete(d.results, key)
+			}
+		default:
+		}
+	}
+}
+
+// handleSendMessage обрабатывает операцию send_message: сообщение, пост
+// или ответ в тред (если передан parentId)
+func (c *Client) handleSendMessage(payload interface{}) {
+	var sendPayload struct {
+		ClientID string `json:"clientId"`
+		Channel  string `json:"channel"`
+		Text     string `json:"text"`
+		IsPost   bool   `json:"isPost"`
+		ParentID string `json:"parentId"`
+
+		Attachments []Attachment `json:"attachments"`
+	}
+	if err := decodePayload(payload, &sendPayload); err != nil {
+		c.sendEvent(WSMessage{Type: "nack", Payload: SendNack{Error: fmt.Sprintf("неверный формат: %v", err)}})
+		return
+	}
+	if sendPayload.ClientID == "" {
+		c.sendEvent(WSMessage{Type: "nack", Payload: SendNack{Error: "не указан clientId"}})
+		return
+	}
+
+	app := c.Hub.app
+	messageID, duplicate, err := c.Hub.sent.do(c.Username, sendPayload.ClientID, func() (string, error) {
+		switch {
+		case len(sendPayload.Attachments) > 0 && (sendPayload.ParentID != "" || sendPayload.IsPost):
+			return "", fmt.Errorf("вложения можно прикреплять только к обычным сообщениям")
+		case len(sendPayload.Attachments) > 0:
+			return app.SendMessageWithAttachments(c.Username, sendPayload.Text, sendPayload.Channel, sendPayload.Attachments)
+		case sendPayload.ParentID != "":
+			return app.SendReply(c.Username, sendPayload.Text, sendPayload.Channel, sendPayload.ParentID)
+		case sendPayload.IsPost:
+			return app.SendPost(c.Username, sendPayload.Text, sendPayload.Channel)
+		default:
+			return app.SendMessage(c.Username, sendPayload.Text, sendPayload.Channel)
+		}
+	})
+	if err != nil {
+		c.sendEvent(WSMessage{Type: "nack", Payload: SendNack{ClientID: sendPayload.ClientID, Error: err.Error()}})
+		return
+	}
+
+	if duplicate {
+		log.Printf("♻️ Повтор отправки %s от %s, сообщение %s", sendPayload.ClientID, c.Username, messageID)
+	}
+	c.sendEvent(WSMessage{Type: "ack", Payload: SendAck{
+		ClientID:  sendPayload.ClientID,
+		MessageID: messageID,
+		Channel:   sendPayload.Channel,
+		Duplicate: duplicate,
+	}})
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestSendDeduper(t *testing.T) {
+	d := newSendDeduper()
+	calls := 0
+	send := func(id string, err error) func() (string, error) {
+		return func() (string, error) {
+			calls++
+			return id, err
+		}
+	}
+
+	tests := []struct {
+		name          string
+		username      string
+		clientID      string
+		send          func() (string, error)
+		wantID        string
+		wantDuplicate bool
+		wantErr       bool
+		wantCalls     int
+	}{
+		{"первая отправка", "al", "c1", send("msg-1", nil), "msg-1", false, false, 1},
+		{"повтор с тем же clientId", "al", "c1", send("msg-2", nil), "msg-1", true, false, 1},
+		{"тот же clientId у другого пользователя", "bob", "c1", send("msg-3", nil), "msg-3", false, false, 2},
+		{"неудачная отправка", "al", "c2", send("", errors.New("нет доступа")), "", false, true, 3},
+		{"повтор после неудачи отправляет снова", "al", "c2", send("msg-4", nil), "msg-4", false, false, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, duplicate, err := d.do(tt.username, tt.clientID, tt.send)
+			if id != tt.wantID || duplicate != tt.wantDuplicate || (err != nil) != tt.wantErr {
+				t.Errorf("do() = %q, %v, %v; ожидалось %q, %v, ошибка %v", id, duplicate, err, tt.wantID, tt.wantDuplicate, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("send вызван %d раз, ожидалось %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+// Одновременные повторы ждут первую попытку, а не отправляют заново
+func TestSendDeduperConcurrent(t *testing.T) {
+	d := newSendDeduper()
+	release := make(chan struct{})
+	var mu sync.Mutex
+	calls := 0
+
+	var wg sync.WaitGroup
+	ids := make([]string, 5)
+	for i := range ids {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ids[i], _, _ = d.do("al", "c1", func() (string, error) {
+				mu.Lock()
+				calls++
+				mu.Unlock()
+				<-release
+				return "msg-1", nil
+			})
+		}(i)
+	}
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("send вызван %d раз, ожидался один", calls)
+	}
+	for i, id := range ids {
+		if id != "msg-1" {
+			t.Errorf("попытка %d получила %q", i, id)
+		}
+	}
+}